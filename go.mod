@@ -1,9 +1,15 @@
 module github.com/gomodule/redigo
 
-go 1.16
+go 1.18
 
 require github.com/stretchr/testify v1.7.0
 
+require (
+	github.com/davecgh/go-spew v1.1.0 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	gopkg.in/yaml.v3 v3.0.0-20200313102051-9f266ea9e77c // indirect
+)
+
 retract (
 	v2.0.0+incompatible // Old development version not maintained or published.
 	v0.0.0-do-not-use // Never used only present due to lack of retract.
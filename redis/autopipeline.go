@@ -0,0 +1,218 @@
+// Copyright 2012 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package redis
+
+import (
+	"errors"
+	"sync"
+)
+
+var _ Conn = (*AutoPipelineConn)(nil)
+
+var errAutoPipelineUnsupported = errors.New("redigo: AutoPipelineConn does not support Send, Flush and Receive")
+
+// AutoPipelineConn is a connection that is safe for concurrent use by
+// multiple goroutines. Commands issued concurrently with Do are written to
+// the underlying connection in pipelines and each reply is returned to the
+// goroutine that issued the command.
+//
+// An error reply is returned only to the goroutine that issued the command.
+// An error on the underlying connection is returned to all goroutines with
+// commands in progress and by all subsequent calls to Do.
+//
+// The Send, Flush and Receive methods are not supported. Commands that
+// change the state of the connection such as MULTI, WATCH, SELECT, SUBSCRIBE
+// and MONITOR and blocking commands such as BLPOP must not be used with an
+// AutoPipelineConn.
+type AutoPipelineConn struct {
+	conn    Conn
+	wake    chan struct{}
+	batches chan []*autoPipelineCall
+	wg      sync.WaitGroup
+
+	mu     sync.Mutex // mu protects the following fields
+	queue  []*autoPipelineCall
+	err    error
+	closed bool
+}
+
+type autoPipelineCall struct {
+	commandName string
+	args        []interface{}
+	reply       interface{}
+	err         error
+	done        chan struct{}
+}
+
+// NewAutoPipelineConn returns an AutoPipelineConn that issues commands on
+// conn. The application must not use conn directly after calling this
+// function.
+func NewAutoPipelineConn(conn Conn) *AutoPipelineConn {
+	p := &AutoPipelineConn{
+		conn:    conn,
+		wake:    make(chan struct{}, 1),
+		batches: make(chan []*autoPipelineCall, 64),
+	}
+	p.wg.Add(2)
+	go p.writeLoop()
+	go p.readLoop()
+	return p
+}
+
+// Do sends a command to the server and returns the received reply. Do can
+// be called concurrently from multiple goroutines.
+func (p *AutoPipelineConn) Do(commandName string, args ...interface{}) (interface{}, error) {
+	if commandName == "" {
+		return nil, nil
+	}
+	call := &autoPipelineCall{commandName: commandName, args: args, done: make(chan struct{})}
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil, errConnClosed
+	}
+	if p.err != nil {
+		err := p.err
+		p.mu.Unlock()
+		return nil, err
+	}
+	p.queue = append(p.queue, call)
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+	p.mu.Unlock()
+	<-call.done
+	return call.reply, call.err
+}
+
+// Send returns an error. AutoPipelineConn does not support Send.
+func (p *AutoPipelineConn) Send(string, ...interface{}) error {
+	return errAutoPipelineUnsupported
+}
+
+// Flush returns an error. AutoPipelineConn does not support Flush.
+func (p *AutoPipelineConn) Flush() error {
+	return errAutoPipelineUnsupported
+}
+
+// Receive returns an error. AutoPipelineConn does not support Receive.
+func (p *AutoPipelineConn) Receive() (interface{}, error) {
+	return nil, errAutoPipelineUnsupported
+}
+
+// Err returns a non-nil value when the connection is not usable.
+func (p *AutoPipelineConn) Err() error {
+	p.mu.Lock()
+	err := p.err
+	closed := p.closed
+	p.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if closed {
+		return errConnClosed
+	}
+	return p.conn.Err()
+}
+
+// Close waits for the commands in progress to complete and closes the
+// underlying connection.
+func (p *AutoPipelineConn) Close() error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+	close(p.wake)
+	p.mu.Unlock()
+	p.wg.Wait()
+	return p.conn.Close()
+}
+
+// setErr records the first error on the underlying connection.
+func (p *AutoPipelineConn) setErr(err error) {
+	p.mu.Lock()
+	if p.err == nil {
+		p.err = err
+	}
+	p.mu.Unlock()
+}
+
+func (p *AutoPipelineConn) writeLoop() {
+	defer p.wg.Done()
+	defer close(p.batches)
+	for range p.wake {
+		p.mu.Lock()
+		batch := p.queue
+		p.queue = nil
+		err := p.err
+		p.mu.Unlock()
+
+		// Commands rejected by Send without an error on the connection,
+		// for example by a value codec, are not written and fail alone.
+		written := batch[:0]
+		for _, call := range batch {
+			if err == nil {
+				if call.err = p.conn.Send(call.commandName, call.args...); call.err == nil {
+					written = append(written, call)
+					continue
+				}
+				if p.conn.Err() != nil {
+					err = call.err
+				}
+			}
+			if call.err == nil {
+				call.err = err
+			}
+			close(call.done)
+		}
+		if err == nil {
+			err = p.conn.Flush()
+		}
+		if err != nil {
+			p.setErr(err)
+			for _, call := range written {
+				call.err = err
+				close(call.done)
+			}
+			continue
+		}
+		if len(written) > 0 {
+			p.batches <- written
+		}
+	}
+}
+
+func (p *AutoPipelineConn) readLoop() {
+	defer p.wg.Done()
+	for batch := range p.batches {
+		for _, call := range batch {
+			p.mu.Lock()
+			err := p.err
+			p.mu.Unlock()
+			if err != nil {
+				call.err = err
+			} else {
+				call.reply, call.err = p.conn.Receive()
+				if call.err != nil && p.conn.Err() != nil {
+					p.setErr(call.err)
+				}
+			}
+			close(call.done)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright 2012 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package redis_test
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/stretchr/testify/require"
+)
+
+// serveEcho serves ECHO commands on the server side of a pipe until the
+// command count reaches limit. Other commands get an error reply.
+func serveEcho(server net.Conn, limit int) {
+	sc := redis.NewConn(server, 0, 0)
+	defer server.Close()
+	for i := 0; limit <= 0 || i < limit; i++ {
+		cmd, err := redis.ByteSlices(sc.Receive())
+		if err != nil {
+			return
+		}
+		var reply string
+		if len(cmd) == 2 && string(cmd[0]) == "ECHO" {
+			reply = "$" + strconv.Itoa(len(cmd[1])) + "\r\n" + string(cmd[1]) + "\r\n"
+		} else {
+			reply = "-ERR unknown command\r\n"
+		}
+		if _, err := server.Write([]byte(reply)); err != nil {
+			return
+		}
+	}
+}
+
+func TestAutoPipelineConn(t *testing.T) {
+	client, server := net.Pipe()
+	go serveEcho(server, 0)
+	c := redis.NewAutoPipelineConn(redis.NewConn(client, 0, 0))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			want := fmt.Sprint(i)
+			if i%10 == 0 {
+				_, err := c.Do("BAD", want)
+				require.EqualError(t, err, "ERR unknown command")
+				return
+			}
+			got, err := redis.String(c.Do("ECHO", want))
+			require.NoError(t, err)
+			require.Equal(t, want, got)
+		}(i)
+	}
+	wg.Wait()
+
+	require.NoError(t, c.Err())
+	require.NoError(t, c.Close())
+	_, err := c.Do("ECHO", "x")
+	require.Error(t, err)
+	require.Error(t, c.Send("ECHO", "x"))
+}
+
+func TestAutoPipelineConnError(t *testing.T) {
+	client, server := net.Pipe()
+	go serveEcho(server, 5)
+	c := redis.NewAutoPipelineConn(redis.NewConn(client, 0, 0))
+	defer c.Close()
+
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		failed int
+	)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.Do("ECHO", "x"); err != nil {
+				mu.Lock()
+				failed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	require.Equal(t, 15, failed)
+	require.Error(t, c.Err())
+	_, err := c.Do("ECHO", "x")
+	require.Error(t, err)
+}
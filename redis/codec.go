@@ -0,0 +1,156 @@
+// Copyright 2012 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package redis
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ValueCodec transforms values stored in Redis, for example to compress or
+// encrypt the values. See DialValueCodec for details.
+type ValueCodec interface {
+	// Encode transforms a value before it's sent to the server.
+	Encode(p []byte) ([]byte, error)
+
+	// Decode transforms a value received from the server.
+	Decode(p []byte) ([]byte, error)
+}
+
+type codecInfo struct {
+	// Arguments at index first, first+step, first+step*2, ... are values.
+	// If step is zero, then only the argument at index first is a value. If
+	// first is less than zero, then the command has no value arguments.
+	first, step int
+
+	// The reply is a value or an array of values.
+	decode bool
+}
+
+var codecInfos = map[string]codecInfo{
+	"SET":    {first: 1},
+	"SETNX":  {first: 1},
+	"SETEX":  {first: 2},
+	"PSETEX": {first: 2},
+	"GETSET": {first: 1, decode: true},
+	"MSET":   {first: 1, step: 2},
+	"MSETNX": {first: 1, step: 2},
+	"GET":    {first: -1, decode: true},
+	"GETDEL": {first: -1, decode: true},
+	"GETEX":  {first: -1, decode: true},
+	"MGET":   {first: -1, decode: true},
+	"HSET":   {first: 2, step: 2},
+	"HSETNX": {first: 2},
+	"HMSET":  {first: 2, step: 2},
+	"HGET":   {first: -1, decode: true},
+	"HMGET":  {first: -1, decode: true},
+	"HVALS":  {first: -1, decode: true},
+	"LPUSH":  {first: 1, step: 1},
+	"RPUSH":  {first: 1, step: 1},
+	"LINDEX": {first: -1, decode: true},
+	"LPOP":   {first: -1, decode: true},
+	"RPOP":   {first: -1, decode: true},
+	"LRANGE": {first: -1, decode: true},
+}
+
+func lookupCodecInfo(commandName string) (codecInfo, bool) {
+	if ci, ok := codecInfos[commandName]; ok {
+		return ci, true
+	}
+	ci, ok := codecInfos[strings.ToUpper(commandName)]
+	return ci, ok
+}
+
+func (ci codecInfo) isValue(i int) bool {
+	if ci.first < 0 || i < ci.first {
+		return false
+	}
+	if ci.step == 0 {
+		return i == ci.first
+	}
+	return (i-ci.first)%ci.step == 0
+}
+
+// argBytes returns the bulk string for arg as sent by conn.writeArg.
+func argBytes(arg interface{}, argumentTypeOK bool) []byte {
+	switch arg := arg.(type) {
+	case string:
+		return []byte(arg)
+	case []byte:
+		return arg
+	case int:
+		return strconv.AppendInt(nil, int64(arg), 10)
+	case int64:
+		return strconv.AppendInt(nil, arg, 10)
+	case float64:
+		return strconv.AppendFloat(nil, arg, 'g', -1, 64)
+	case bool:
+		if arg {
+			return []byte("1")
+		}
+		return []byte("0")
+	case nil:
+		return []byte{}
+	case Argument:
+		if argumentTypeOK {
+			return argBytes(arg.RedisArg(), false)
+		}
+	}
+	var buf bytes.Buffer
+	fmt.Fprint(&buf, arg)
+	return buf.Bytes()
+}
+
+// encodeArgs returns args with the values of the command encoded by codec.
+func encodeArgs(codec ValueCodec, cmd string, args []interface{}) ([]interface{}, error) {
+	ci, ok := lookupCodecInfo(cmd)
+	if !ok || ci.first < 0 {
+		return args, nil
+	}
+	encoded := make([]interface{}, len(args))
+	for i, arg := range args {
+		if !ci.isValue(i) {
+			encoded[i] = arg
+			continue
+		}
+		p, err := codec.Encode(argBytes(arg, true))
+		if err != nil {
+			return nil, err
+		}
+		encoded[i] = p
+	}
+	return encoded, nil
+}
+
+// decodeReply returns reply with the values decoded by codec.
+func decodeReply(codec ValueCodec, reply interface{}) (interface{}, error) {
+	switch reply := reply.(type) {
+	case []byte:
+		return codec.Decode(reply)
+	case []interface{}:
+		for i, v := range reply {
+			if p, ok := v.([]byte); ok {
+				p, err := codec.Decode(p)
+				if err != nil {
+					return nil, err
+				}
+				reply[i] = p
+			}
+		}
+	}
+	return reply, nil
+}
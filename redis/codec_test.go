@@ -0,0 +1,60 @@
+// Copyright 2012 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package redis_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/stretchr/testify/require"
+)
+
+// prefixCodec encodes values by adding a prefix.
+type prefixCodec struct{}
+
+func (prefixCodec) Encode(p []byte) ([]byte, error) {
+	return append([]byte("z:"), p...), nil
+}
+
+func (prefixCodec) Decode(p []byte) ([]byte, error) {
+	if !bytes.HasPrefix(p, []byte("z:")) {
+		return nil, errors.New("missing prefix")
+	}
+	return p[2:], nil
+}
+
+func TestValueCodec(t *testing.T) {
+	var buf bytes.Buffer
+	c, err := redis.Dial("", "", redis.DialValueCodec(prefixCodec{}),
+		dialTestConn("+OK\r\n*3\r\n$3\r\nz:a\r\n$-1\r\n$3\r\nz:1\r\n$1\r\nx\r\n", &buf))
+	require.NoError(t, err)
+
+	_, err = c.Do("MSET", "k1", "a", "k2", 1)
+	require.NoError(t, err)
+	require.Equal(t, "*5\r\n$4\r\nMSET\r\n$2\r\nk1\r\n$3\r\nz:a\r\n$2\r\nk2\r\n$3\r\nz:1\r\n", buf.String())
+
+	require.NoError(t, c.Send("MGET", "k1", "k3", "k2"))
+	require.NoError(t, c.Send("GET", "k4"))
+	require.NoError(t, c.Flush())
+	values, err := redis.ByteSlices(c.Receive())
+	require.NoError(t, err)
+	require.Equal(t, [][]byte{[]byte("a"), nil, []byte("1")}, values)
+
+	_, err = c.Receive()
+	require.EqualError(t, err, "missing prefix")
+	require.NoError(t, c.Err())
+}
@@ -0,0 +1,1232 @@
+// Copyright 2012 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package redis
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// InterCard executes the SINTERCARD or ZINTERCARD command, as specified by
+// commandName, on the given keys and returns the cardinality of the
+// intersection. The numkeys argument is computed from keys. If limit is
+// greater than zero, then the LIMIT option is sent to the server.
+func InterCard(c Conn, commandName string, keys []string, limit int) (int64, error) {
+	args := make([]interface{}, 0, len(keys)+3)
+	args = append(args, len(keys))
+	for _, k := range keys {
+		args = append(args, k)
+	}
+	if limit > 0 {
+		args = append(args, "LIMIT", limit)
+	}
+	return Int64(c.Do(commandName, args...))
+}
+
+// MGetChunked gets the values of keys using MGET commands of at most
+// chunkSize keys each. The commands are pipelined and the values are
+// returned in the order of keys with nil for missing keys. If chunkSize is
+// less than or equal to zero, then a single MGET command is used.
+func MGetChunked(c Conn, keys []string, chunkSize int) ([][]byte, error) {
+	if len(keys) == 0 {
+		return [][]byte{}, nil
+	}
+	if chunkSize <= 0 {
+		chunkSize = len(keys)
+	}
+
+	n := 0
+	for i := 0; i < len(keys); i += chunkSize {
+		end := i + chunkSize
+		if end > len(keys) {
+			end = len(keys)
+		}
+		args := make([]interface{}, end-i)
+		for j, k := range keys[i:end] {
+			args[j] = k
+		}
+		if err := c.Send("MGET", args...); err != nil {
+			return nil, err
+		}
+		n++
+	}
+	if err := c.Flush(); err != nil {
+		return nil, err
+	}
+
+	// Receive all replies to keep the connection usable after an error.
+	result := make([][]byte, 0, len(keys))
+	var err error
+	for i := 0; i < n; i++ {
+		values, e := ByteSlices(c.Receive())
+		if e != nil {
+			if err == nil {
+				err = e
+			}
+			continue
+		}
+		result = append(result, values...)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(result) != len(keys) {
+		return nil, fmt.Errorf("redigo: MGetChunked got %d values for %d keys", len(result), len(keys))
+	}
+	return result, nil
+}
+
+// GetDel gets the value of key and deletes the key using the GETDEL
+// command. If the key does not exist, then GetDel returns ErrNil.
+func GetDel(c Conn, key string) ([]byte, error) {
+	return Bytes(c.Do("GETDEL", key))
+}
+
+// GetExOptions specifies the expiration options for the GETEX command. At
+// most one of the options can be set. If no option is set, then GETEX does
+// not change the expiration of the key.
+type GetExOptions struct {
+	// EX sets the expiration to the duration truncated to seconds. A non-zero
+	// EX less than one second is an error.
+	EX time.Duration
+
+	// PX sets the expiration to the duration truncated to milliseconds. A
+	// non-zero PX less than one millisecond is an error.
+	PX time.Duration
+
+	// EXAT sets the expiration to the time in seconds.
+	EXAT time.Time
+
+	// PXAT sets the expiration to the time in milliseconds.
+	PXAT time.Time
+
+	// Persist removes the expiration.
+	Persist bool
+}
+
+var errGetExOptions = errors.New("redigo: GetExOptions EX, PX, EXAT, PXAT and Persist are mutually exclusive")
+
+func (opts GetExOptions) args() ([]interface{}, error) {
+	if opts.EX != 0 && opts.EX < time.Second {
+		return nil, fmt.Errorf("redigo: GetExOptions EX must be at least one second, got %v", opts.EX)
+	}
+	if opts.PX != 0 && opts.PX < time.Millisecond {
+		return nil, fmt.Errorf("redigo: GetExOptions PX must be at least one millisecond, got %v", opts.PX)
+	}
+	var args []interface{}
+	if opts.EX != 0 {
+		args = append(args, "EX", int64(opts.EX/time.Second))
+	}
+	if opts.PX != 0 {
+		args = append(args, "PX", int64(opts.PX/time.Millisecond))
+	}
+	if !opts.EXAT.IsZero() {
+		args = append(args, "EXAT", opts.EXAT.Unix())
+	}
+	if !opts.PXAT.IsZero() {
+		args = append(args, "PXAT", opts.PXAT.UnixNano()/int64(time.Millisecond))
+	}
+	if opts.Persist {
+		args = append(args, "PERSIST")
+	}
+	if len(args) > 2 || (opts.Persist && len(args) > 1) {
+		return nil, errGetExOptions
+	}
+	return args, nil
+}
+
+// GetEx gets the value of key and sets the expiration of key as specified
+// by opts using the GETEX command. If the key does not exist, then GetEx
+// returns ErrNil.
+func GetEx(c Conn, key string, opts GetExOptions) ([]byte, error) {
+	args, err := opts.args()
+	if err != nil {
+		return nil, err
+	}
+	return Bytes(c.Do("GETEX", append([]interface{}{key}, args...)...))
+}
+
+// NoExpiry is the TTL returned by GetWithTTL for a key without an expiration.
+const NoExpiry time.Duration = -1
+
+var getWithTTLScript = NewScript(1, `return {redis.call('GET', KEYS[1]), redis.call('PTTL', KEYS[1])}`)
+
+// GetWithTTL atomically gets the value and the remaining time to live of key
+// in a single round trip. If the key does not exist, then GetWithTTL returns
+// ErrNil. If the key does not have an expiration, then the returned TTL is
+// NoExpiry.
+func GetWithTTL(c Conn, key string) ([]byte, time.Duration, error) {
+	values, err := Values(getWithTTLScript.Do(c, key))
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(values) != 2 {
+		return nil, 0, fmt.Errorf("redigo: GetWithTTL expects two values, got %d", len(values))
+	}
+	if values[0] == nil {
+		return nil, 0, ErrNil
+	}
+	v, err := Bytes(values[0], nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	ms, err := Int64(values[1], nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	if ms < 0 {
+		return v, NoExpiry, nil
+	}
+	return v, time.Duration(ms) * time.Millisecond, nil
+}
+
+var moveWithExpiryScript = NewScript(2, `
+if redis.call('EXISTS', KEYS[1]) == 0 then
+  return 0
+end
+redis.call('RENAME', KEYS[1], KEYS[2])
+if tonumber(ARGV[1]) > 0 then
+  redis.call('PEXPIRE', KEYS[2], ARGV[1])
+else
+  redis.call('PERSIST', KEYS[2])
+end
+return 1
+`)
+
+// MoveWithExpiry atomically renames src to dst and sets the time to live of
+// dst to ttl in a single round trip. The value at dst, if any, is replaced.
+// A zero ttl removes the expiration from dst. A non-zero ttl less than one
+// millisecond is an error. MoveWithExpiry returns false if src does not
+// exist. Use MoveWithExpiry to promote a staged key. In a cluster, src and
+// dst must be in the same hash slot.
+func MoveWithExpiry(c Conn, src, dst string, ttl time.Duration) (bool, error) {
+	if ttl < 0 || (ttl > 0 && ttl < time.Millisecond) {
+		return false, fmt.Errorf("redigo: MoveWithExpiry requires a zero TTL or a TTL of at least one millisecond, got %v", ttl)
+	}
+	return Bool(moveWithExpiryScript.Do(c, src, dst, int64(ttl/time.Millisecond)))
+}
+
+// XAck acknowledges the messages with the given IDs in the consumer group of
+// stream using the XACK command. XAck returns the number of messages that
+// were successfully acknowledged.
+func XAck(c Conn, stream, group string, ids ...string) (int64, error) {
+	args := make([]interface{}, 0, len(ids)+2)
+	args = append(args, stream, group)
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	return Int64(c.Do("XACK", args...))
+}
+
+// XAckDel acknowledges and deletes the messages with the given IDs in the
+// consumer group of stream using the XACKDEL command added in Redis 8.2. The
+// policy argument is one of KEEPREF, DELREF or ACKED. If policy is "", then
+// the server default is used. XAckDel returns the per ID result codes in the
+// order of ids.
+func XAckDel(c Conn, stream, group, policy string, ids ...string) ([]int64, error) {
+	args := make([]interface{}, 0, len(ids)+5)
+	args = append(args, stream, group)
+	if policy != "" {
+		args = append(args, policy)
+	}
+	args = append(args, "IDS", len(ids))
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	return Int64s(c.Do("XACKDEL", args...))
+}
+
+// ErrLFUNotEnabled is returned by ObjectFreq when the server is not
+// configured with an LFU maxmemory-policy.
+var ErrLFUNotEnabled = errors.New("redigo: OBJECT FREQ requires an LFU maxmemory-policy")
+
+// lfuError is the error returned by ObjectFreq when the server does not
+// track access frequency. The error matches ErrLFUNotEnabled with errors.Is
+// and unwraps to the error reply from the server.
+type lfuError struct {
+	err error
+}
+
+func (e *lfuError) Error() string { return ErrLFUNotEnabled.Error() + ": " + e.err.Error() }
+
+func (e *lfuError) Is(target error) bool { return target == ErrLFUNotEnabled }
+
+func (e *lfuError) Unwrap() error { return e.err }
+
+// ObjectFreq returns the logarithmic access frequency counter of key using
+// the OBJECT FREQ command. If the server does not track access frequency,
+// then the returned error matches ErrLFUNotEnabled with errors.Is and wraps
+// the Error reply from the server.
+func ObjectFreq(c Conn, key string) (int64, error) {
+	n, err := Int64(c.Do("OBJECT", "FREQ", key))
+	var e Error
+	if errors.As(err, &e) && strings.Contains(strings.ToUpper(string(e)), "LFU") {
+		return 0, &lfuError{err: err}
+	}
+	return n, err
+}
+
+// isOptionRejected reports whether err is the error returned by servers
+// that do not support an option of a command.
+func isOptionRejected(err error) bool {
+	var e Error
+	if !errors.As(err, &e) {
+		return false
+	}
+	s := string(e)
+	return strings.Contains(s, "syntax error") || strings.Contains(s, "wrong number of arguments")
+}
+
+// ExpireCond specifies the condition for setting the expiration of a key.
+type ExpireCond int
+
+// Conditions for Expire. The conditions require Redis 7.0 or later.
+const (
+	// ExpireAlways sets the expiration unconditionally.
+	ExpireAlways ExpireCond = iota
+
+	// ExpireNX sets the expiration only when the key has no expiration.
+	ExpireNX
+
+	// ExpireXX sets the expiration only when the key has an expiration.
+	ExpireXX
+
+	// ExpireGT sets the expiration only when the new expiration is greater
+	// than the current one.
+	ExpireGT
+
+	// ExpireLT sets the expiration only when the new expiration is less than
+	// the current one.
+	ExpireLT
+)
+
+var expireCondArgs = [...]string{
+	ExpireNX: "NX",
+	ExpireXX: "XX",
+	ExpireGT: "GT",
+	ExpireLT: "LT",
+}
+
+// Expire sets the expiration of key to ttl with the condition cond using the
+// EXPIRE command for a whole number of seconds and the PEXPIRE command with
+// millisecond resolution otherwise. Expire returns true if the expiration
+// was set and false if the key does not exist or the condition was not met.
+// Expire returns an error without sending a command if ttl is less than a
+// millisecond because the server deletes a key with a TTL of zero. Use Del
+// to delete keys. If the server does not support the condition, then Expire
+// returns an error stating that Redis 7.0 is required.
+func Expire(c Conn, key string, ttl time.Duration, cond ExpireCond) (bool, error) {
+	if ttl < time.Millisecond {
+		return false, fmt.Errorf("redigo: Expire requires a TTL of at least one millisecond, got %v", ttl)
+	}
+	cmd := "EXPIRE"
+	args := []interface{}{key, int64(ttl / time.Second)}
+	if ttl%time.Second != 0 {
+		cmd = "PEXPIRE"
+		args[1] = int64(ttl / time.Millisecond)
+	}
+	if cond != ExpireAlways {
+		if cond < 0 || int(cond) >= len(expireCondArgs) {
+			return false, fmt.Errorf("redigo: invalid ExpireCond %d", cond)
+		}
+		args = append(args, expireCondArgs[cond])
+	}
+	ok, err := IntBool(c.Do(cmd, args...))
+	if cond != ExpireAlways && isOptionRejected(err) {
+		return false, fmt.Errorf("redigo: %s %s requires Redis 7.0 or later: %w", cmd, expireCondArgs[cond], err)
+	}
+	return ok, err
+}
+
+// BitCountUnit specifies the unit of a BitCountRange.
+type BitCountUnit int
+
+// Units for BitCountRange.
+const (
+	// BitCountByte specifies a range of bytes.
+	BitCountByte BitCountUnit = iota
+
+	// BitCountBit specifies a range of bits. The unit requires Redis 7.0 or
+	// later.
+	BitCountBit
+)
+
+// BitCountRange specifies the inclusive range for the BITCOUNT command.
+// Negative positions count from the end of the string.
+type BitCountRange struct {
+	Start, End int64
+	Unit       BitCountUnit
+}
+
+// BitCount counts the set bits in the string value of key using the
+// BITCOUNT command. If r is nil, then the whole string is counted. If the
+// server does not support the BIT unit, then BitCount returns an error
+// stating that Redis 7.0 is required.
+func BitCount(c Conn, key string, r *BitCountRange) (int64, error) {
+	if r == nil {
+		return Int64(c.Do("BITCOUNT", key))
+	}
+	if r.Unit == BitCountByte {
+		return Int64(c.Do("BITCOUNT", key, r.Start, r.End))
+	}
+	if r.Unit != BitCountBit {
+		return 0, fmt.Errorf("redigo: invalid BitCountUnit %d", r.Unit)
+	}
+	n, err := Int64(c.Do("BITCOUNT", key, r.Start, r.End, "BIT"))
+	if isOptionRejected(err) {
+		return 0, fmt.Errorf("redigo: BITCOUNT BIT requires Redis 7.0 or later: %w", err)
+	}
+	return n, err
+}
+
+// Per field results of HExpire.
+const (
+	// HExpireNoField indicates that the field does not exist.
+	HExpireNoField = -2
+
+	// HExpireNotSet indicates that the expiration was not set because a
+	// condition was not met.
+	HExpireNotSet = 0
+
+	// HExpireSet indicates that the expiration was set.
+	HExpireSet = 1
+
+	// HExpireDeleted indicates that the field was deleted because the
+	// expiration is zero or in the past.
+	HExpireDeleted = 2
+)
+
+// NoField is the TTL returned by HTTL for a field that does not exist.
+const NoField time.Duration = -2
+
+// hashFieldsArgs returns the arguments for key followed by opts and the
+// FIELDS numfields field ... argument of hash field expiration commands.
+func hashFieldsArgs(key string, opts []interface{}, fields []string) []interface{} {
+	args := make([]interface{}, 0, len(opts)+len(fields)+3)
+	args = append(args, key)
+	args = append(args, opts...)
+	args = append(args, "FIELDS", len(fields))
+	for _, f := range fields {
+		args = append(args, f)
+	}
+	return args
+}
+
+// HExpire sets the expiration of the hash fields to ttl using the HEXPIRE
+// command for a whole number of seconds and the HPEXPIRE command with
+// millisecond resolution otherwise. Both commands were added in Redis 7.4.
+// HExpire returns a result for each field in the order of fields. The result
+// is one of HExpireNoField, HExpireNotSet, HExpireSet or HExpireDeleted.
+// HExpire returns an error without sending a command if ttl is less than a
+// millisecond because the server deletes fields with a TTL of zero.
+func HExpire(c Conn, key string, ttl time.Duration, fields ...string) ([]int, error) {
+	if ttl < time.Millisecond {
+		return nil, fmt.Errorf("redigo: HExpire requires a TTL of at least one millisecond, got %v", ttl)
+	}
+	if ttl%time.Second != 0 {
+		return Ints(c.Do("HPEXPIRE", hashFieldsArgs(key, []interface{}{int64(ttl / time.Millisecond)}, fields)...))
+	}
+	return Ints(c.Do("HEXPIRE", hashFieldsArgs(key, []interface{}{int64(ttl / time.Second)}, fields)...))
+}
+
+// HTTL returns the remaining time to live of the hash fields using the HTTL
+// command added in Redis 7.4. HTTL returns a TTL for each field in the order
+// of fields. The TTL is NoField for a field that does not exist and NoExpiry
+// for a field without an expiration.
+func HTTL(c Conn, key string, fields ...string) ([]time.Duration, error) {
+	ttls, err := Int64s(c.Do("HTTL", hashFieldsArgs(key, nil, fields)...))
+	if err != nil {
+		return nil, err
+	}
+	result := make([]time.Duration, len(ttls))
+	for i, ttl := range ttls {
+		switch {
+		case ttl == -2:
+			result[i] = NoField
+		case ttl < 0:
+			result[i] = NoExpiry
+		default:
+			result[i] = time.Duration(ttl) * time.Second
+		}
+	}
+	return result, nil
+}
+
+// ZPop removes and returns up to count members with the lowest scores when
+// min is true or the highest scores when min is false using the ZPOPMIN or
+// ZPOPMAX command. If count is less than or equal to zero, then the count
+// argument is not sent and the server pops one member. An empty slice is
+// returned when the sorted set is empty or does not exist.
+func ZPop(c Conn, key string, min bool, count int) ([]ZMember, error) {
+	commandName := "ZPOPMAX"
+	if min {
+		commandName = "ZPOPMIN"
+	}
+	if count <= 0 {
+		return ZPopResults(c.Do(commandName, key))
+	}
+	return ZPopResults(c.Do(commandName, key, count))
+}
+
+// ReplicationInfo returns the replication status of the server using the
+// INFO replication command.
+func ReplicationInfo(c Conn) (ReplicationStatus, error) {
+	var status ReplicationStatus
+	lines, err := Lines(c.Do("INFO", "replication"))
+	if err != nil {
+		return status, err
+	}
+	for _, line := range lines {
+		i := strings.IndexByte(line, ':')
+		if i < 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		k, v := line[:i], line[i+1:]
+		switch {
+		case k == "role":
+			status.Role = v
+		case k == "connected_slaves":
+			status.ConnectedSlaves, err = strconv.Atoi(v)
+		case k == "master_repl_offset":
+			status.MasterReplOffset, err = strconv.ParseInt(v, 10, 64)
+		case strings.HasPrefix(k, "slave") && isDigits(k[len("slave"):]):
+			var si SlaveInfo
+			si, err = parseSlaveInfo(v)
+			status.Slaves = append(status.Slaves, si)
+		}
+		if err != nil {
+			return status, fmt.Errorf("redigo: ReplicationInfo %s: %w", k, err)
+		}
+	}
+	return status, nil
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// parseSlaveInfo parses a replica line value such as
+// "ip=10.0.0.2,port=6380,state=online,offset=123,lag=0".
+func parseSlaveInfo(s string) (SlaveInfo, error) {
+	var si SlaveInfo
+	for _, field := range strings.Split(s, ",") {
+		i := strings.IndexByte(field, '=')
+		if i < 0 {
+			return si, fmt.Errorf("unexpected field %q", field)
+		}
+		k, v := field[:i], field[i+1:]
+		var err error
+		switch k {
+		case "ip":
+			si.IP = v
+		case "port":
+			si.Port, err = strconv.Atoi(v)
+		case "state":
+			si.State = v
+		case "offset":
+			si.Offset, err = strconv.ParseInt(v, 10, 64)
+		case "lag":
+			si.Lag, err = strconv.ParseInt(v, 10, 64)
+		}
+		if err != nil {
+			return si, err
+		}
+	}
+	return si, nil
+}
+
+// HSet sets fields in the hash stored at key using the HSET command and
+// returns the number of fields that were added. Fields that already exist
+// are updated and are not counted. The fields argument is flattened with
+// Args.AddFlat and can be a map, a struct, a pointer to a struct or a slice
+// of alternating field names and values.
+func HSet(c Conn, key string, fields interface{}) (int64, error) {
+	args := Args{key}.AddFlat(fields)
+	if len(args) < 3 || len(args)%2 != 1 {
+		return 0, fmt.Errorf("redigo: HSet expects field value pairs, got %d values", len(args)-1)
+	}
+	return Int64(c.Do("HSET", args...))
+}
+
+// XAdd appends an entry with the given fields to stream using the XADD
+// command and returns the ID of the entry. Use "*" as id to have the server
+// generate the ID. The fields argument is flattened with Args.AddFlat and
+// can be a map, a struct, a pointer to a struct or a slice of alternating
+// field names and values.
+func XAdd(c Conn, stream, id string, fields interface{}) (string, error) {
+	args := Args{stream, id}.AddFlat(fields)
+	if len(args) < 4 || len(args)%2 != 0 {
+		return "", fmt.Errorf("redigo: XAdd expects field value pairs, got %d values", len(args)-2)
+	}
+	return String(c.Do("XADD", args...))
+}
+
+// XParseID parses a stream entry ID of the form <ms>-<seq> into its
+// millisecond time and sequence number parts. An ID without a sequence
+// number has sequence number zero.
+func XParseID(id string) (ms int64, seq int64, err error) {
+	msPart, seqPart := id, ""
+	if i := strings.IndexByte(id, '-'); i >= 0 {
+		msPart, seqPart = id[:i], id[i+1:]
+	}
+	if ms, err = strconv.ParseInt(msPart, 10, 64); err != nil || ms < 0 {
+		return 0, 0, fmt.Errorf("redigo: invalid stream ID %q", id)
+	}
+	if seqPart != "" {
+		if seq, err = strconv.ParseInt(seqPart, 10, 64); err != nil || seq < 0 {
+			return 0, 0, fmt.Errorf("redigo: invalid stream ID %q", id)
+		}
+	}
+	return ms, seq, nil
+}
+
+// GetRange returns the bytes of the string value of key between the
+// inclusive offsets start and end using the GETRANGE command. Negative
+// offsets count from the end of the string. The bytes are returned exactly
+// as stored. An empty range or a missing key returns an empty slice, not
+// ErrNil.
+func GetRange(c Conn, key string, start, end int) ([]byte, error) {
+	p, err := Bytes(c.Do("GETRANGE", key, start, end))
+	if err == ErrNil || (err == nil && p == nil) {
+		return []byte{}, nil
+	}
+	return p, err
+}
+
+// SetRange overwrites the string value of key starting at offset with value
+// using the SETRANGE command and returns the length of the string after the
+// command.
+func SetRange(c Conn, key string, offset int, value []byte) (int64, error) {
+	return Int64(c.Do("SETRANGE", key, offset, value))
+}
+
+// GetBit returns the bit value, 0 or 1, at offset in the string value of key
+// using the GETBIT command. Offsets after the end of the string and missing
+// keys return 0.
+func GetBit(c Conn, key string, offset int64) (int, error) {
+	if offset < 0 {
+		return 0, fmt.Errorf("redigo: GetBit requires a non-negative offset, got %d", offset)
+	}
+	return Int(c.Do("GETBIT", key, offset))
+}
+
+// SetBit sets the bit at offset in the string value of key to value using
+// the SETBIT command and returns the previous bit value. SetBit returns an
+// error without sending the command if value is not 0 or 1 or if offset is
+// negative.
+func SetBit(c Conn, key string, offset int64, value int) (int, error) {
+	if value != 0 && value != 1 {
+		return 0, fmt.Errorf("redigo: SetBit requires a bit value of 0 or 1, got %d", value)
+	}
+	if offset < 0 {
+		return 0, fmt.Errorf("redigo: SetBit requires a non-negative offset, got %d", offset)
+	}
+	return Int(c.Do("SETBIT", key, offset, value))
+}
+
+// BitPosRange specifies the inclusive range for the BITPOS command. Negative
+// positions count from the end of the string. Use End -1 to search to the
+// end of the string.
+type BitPosRange struct {
+	Start, End int64
+	Unit       BitCountUnit
+}
+
+// BitPos returns the position of the first bit set to bit in the string
+// value of key using the BITPOS command. If r is nil, then the whole string
+// is searched. If no bit is found, then BitPos returns ErrNil. If the server
+// does not support the BIT unit, then BitPos returns an error stating that
+// Redis 7.0 is required.
+//
+// When searching for a clear bit without a range, the server considers the
+// string padded with clear bits and returns the position after the string
+// instead of reporting that no bit is found.
+func BitPos(c Conn, key string, bit int, r *BitPosRange) (int64, error) {
+	args := []interface{}{key, bit}
+	if r != nil {
+		args = append(args, r.Start, r.End)
+		switch r.Unit {
+		case BitCountByte:
+		case BitCountBit:
+			args = append(args, "BIT")
+		default:
+			return 0, fmt.Errorf("redigo: invalid BitCountUnit %d", r.Unit)
+		}
+	}
+	n, err := Int64(c.Do("BITPOS", args...))
+	if r != nil && r.Unit == BitCountBit && isOptionRejected(err) {
+		return 0, fmt.Errorf("redigo: BITPOS BIT requires Redis 7.0 or later: %w", err)
+	}
+	if err == nil && n < 0 {
+		return 0, ErrNil
+	}
+	return n, err
+}
+
+// ClientKillFilter specifies the clients closed by the CLIENT KILL command.
+// A client must match all of the set filters. At least one of ID, Addr,
+// LAddr, Type, User and MaxAge must be set.
+type ClientKillFilter struct {
+	// ID matches the client with the ID returned by CLIENT ID.
+	ID int64
+
+	// Addr matches the client connected from the address ip:port.
+	Addr string
+
+	// LAddr matches the clients connected to the local address ip:port.
+	LAddr string
+
+	// Type matches the clients of the type normal, master, replica or
+	// pubsub.
+	Type string
+
+	// User matches the clients authenticated as the ACL user.
+	User string
+
+	// MaxAge matches the clients connected for longer than the duration in
+	// seconds.
+	MaxAge time.Duration
+
+	// SkipMe is "yes" or "no" to skip or include the calling client. The
+	// server skips the calling client when SkipMe is empty.
+	SkipMe string
+}
+
+var errClientKillFilter = errors.New("redigo: ClientKillFilter requires at least one filter")
+
+func (f ClientKillFilter) args() ([]interface{}, error) {
+	var args []interface{}
+	if f.ID != 0 {
+		args = append(args, "ID", f.ID)
+	}
+	if f.Addr != "" {
+		args = append(args, "ADDR", f.Addr)
+	}
+	if f.LAddr != "" {
+		args = append(args, "LADDR", f.LAddr)
+	}
+	if f.Type != "" {
+		args = append(args, "TYPE", f.Type)
+	}
+	if f.User != "" {
+		args = append(args, "USER", f.User)
+	}
+	if f.MaxAge != 0 {
+		args = append(args, "MAXAGE", int64(f.MaxAge/time.Second))
+	}
+	if len(args) == 0 {
+		return nil, errClientKillFilter
+	}
+	switch f.SkipMe {
+	case "":
+	case "yes", "no":
+		args = append(args, "SKIPME", f.SkipMe)
+	default:
+		return nil, fmt.Errorf("redigo: invalid ClientKillFilter SkipMe %q", f.SkipMe)
+	}
+	return args, nil
+}
+
+// ClientKill closes the clients matching f using the CLIENT KILL command and
+// returns the number of clients closed.
+func ClientKill(c Conn, f ClientKillFilter) (int64, error) {
+	args, err := f.args()
+	if err != nil {
+		return 0, err
+	}
+	return Int64(c.Do("CLIENT", append([]interface{}{"KILL"}, args...)...))
+}
+
+// ClientKillAddr closes the client connected from the address ip:port using
+// the legacy form of the CLIENT KILL command. ClientKillAddr returns false if
+// no client is connected from the address.
+func ClientKillAddr(c Conn, addr string) (bool, error) {
+	reply, err := c.Do("CLIENT", "KILL", addr)
+	var e Error
+	if errors.As(err, &e) && strings.Contains(string(e), "No such client") {
+		return false, nil
+	}
+	if err := StatusReply(reply, err, "OK"); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// SPop removes and returns up to count random members of the set at key
+// using the SPOP command with a count. SPop returns an empty slice when the
+// set is empty or the key does not exist.
+func SPop(c Conn, key string, count int) ([][]byte, error) {
+	members, err := ByteSlices(c.Do("SPOP", key, count))
+	if err == ErrNil || (err == nil && members == nil) {
+		return [][]byte{}, nil
+	}
+	return members, err
+}
+
+// SPopOne removes and returns a random member of the set at key using the
+// SPOP command without a count. If the set is empty or the key does not
+// exist, then SPopOne returns ErrNil.
+func SPopOne(c Conn, key string) ([]byte, error) {
+	return Bytes(c.Do("SPOP", key))
+}
+
+// ErrTimeout is returned by blocking command helpers when the command
+// times out before an element is available.
+var ErrTimeout = errors.New("redigo: blocking command timed out")
+
+// BLMPop pops an element from the first non-empty list in keys using the
+// BLMPOP command and returns the key of the list and the popped element.
+// The direction is LEFT or RIGHT. BLMPop blocks for up to timeout, or
+// indefinitely when timeout is zero, and returns ErrTimeout if no element
+// is available. The connection read timeout must be longer than timeout.
+func BLMPop(c Conn, timeout time.Duration, direction string, keys ...string) (key string, values []string, err error) {
+	args := make([]interface{}, 0, len(keys)+3)
+	args = append(args, timeout.Seconds(), len(keys))
+	for _, k := range keys {
+		args = append(args, k)
+	}
+	args = append(args, direction)
+	key, values, err = KeyValues(c.Do("BLMPOP", args...))
+	if err == ErrNil {
+		return "", nil, ErrTimeout
+	}
+	return key, values, err
+}
+
+var (
+	// ErrKeyMissing is returned by TTL and PTTL when the key does not exist.
+	ErrKeyMissing = errors.New("redigo: key does not exist")
+
+	// ErrNoExpire is returned by TTL and PTTL when the key exists but does
+	// not have an expiration.
+	ErrNoExpire = errors.New("redigo: key does not have an expiration")
+)
+
+// ttl converts the reply of the TTL or PTTL command in units of unit to a
+// duration.
+func ttl(reply interface{}, err error, unit time.Duration) (time.Duration, error) {
+	n, err := Int64(reply, err)
+	if err != nil {
+		return 0, err
+	}
+	switch {
+	case n == -2:
+		return 0, ErrKeyMissing
+	case n == -1:
+		return 0, ErrNoExpire
+	case n < 0:
+		return 0, fmt.Errorf("redigo: unexpected TTL %d", n)
+	}
+	return time.Duration(n) * unit, nil
+}
+
+// TTL returns the remaining time to live of key with a resolution of one
+// second using the TTL command. If the key does not exist, then TTL returns
+// ErrKeyMissing. If the key does not have an expiration, then TTL returns
+// ErrNoExpire.
+func TTL(c Conn, key string) (time.Duration, error) {
+	reply, err := c.Do("TTL", key)
+	return ttl(reply, err, time.Second)
+}
+
+// PTTL is like TTL but uses the PTTL command for a resolution of one
+// millisecond.
+func PTTL(c Conn, key string) (time.Duration, error) {
+	reply, err := c.Do("PTTL", key)
+	return ttl(reply, err, time.Millisecond)
+}
+
+// Del removes keys using the DEL command and returns the number of keys
+// removed. The server reclaims the memory of the values before replying.
+func Del(c Conn, keys ...string) (int64, error) {
+	return Int64(c.Do("DEL", Args{}.AddFlat(keys)...))
+}
+
+// Unlink removes keys using the UNLINK command and returns the number of
+// keys removed. Unlike DEL, the server reclaims the memory of large values
+// in the background, so UNLINK does not block the server.
+func Unlink(c Conn, keys ...string) (int64, error) {
+	return Int64(c.Do("UNLINK", Args{}.AddFlat(keys)...))
+}
+
+// deleteMatchingCount is the COUNT hint for the SCAN command issued by
+// DeleteMatching.
+const deleteMatchingCount = 100
+
+// DeleteMatching removes the keys matching pattern and returns the number of
+// keys removed. DeleteMatching iterates over the keys with the SCAN command
+// and removes the keys returned by each SCAN call in a batch with UNLINK if
+// useUnlink is set or DEL otherwise. Keys added or removed by other clients
+// during the iteration may or may not be removed.
+func DeleteMatching(c Conn, pattern string, useUnlink bool) (int64, error) {
+	del := Del
+	if useUnlink {
+		del = Unlink
+	}
+	var total int64
+	var cursor uint64
+	for {
+		var keys []string
+		var err error
+		cursor, keys, err = ScanResult(c.Do("SCAN", cursor, "MATCH", pattern, "COUNT", deleteMatchingCount))
+		if err != nil {
+			return total, err
+		}
+		if len(keys) > 0 {
+			n, err := del(c, keys...)
+			total += n
+			if err != nil {
+				return total, err
+			}
+		}
+		if cursor == 0 {
+			return total, nil
+		}
+	}
+}
+
+// GeoSearchQuery specifies the center, shape, order and count of a
+// GEOSEARCH or GEOSEARCHSTORE query. Set exactly one of FromMember and
+// FromLonLat for the center and exactly one of Radius and Width and Height
+// for the shape.
+type GeoSearchQuery struct {
+	// FromMember centers the query at the position of the member.
+	FromMember string
+
+	// FromLonLat centers the query at Longitude and Latitude.
+	FromLonLat          bool
+	Longitude, Latitude float64
+
+	// Radius searches in a circle with the radius.
+	Radius float64
+
+	// Width and Height search in an axis-aligned rectangle.
+	Width, Height float64
+
+	// Unit is the unit of the distances: m, km, ft or mi. The default is m.
+	Unit string
+
+	// Order is ASC or DESC to sort the results by distance from the
+	// center. The results are not sorted when Order is empty.
+	Order string
+
+	// Count limits the number of results when greater than zero. Any
+	// returns the first Count results found instead of the nearest.
+	Count int
+	Any   bool
+}
+
+func (q GeoSearchQuery) args() ([]interface{}, error) {
+	var args []interface{}
+	switch {
+	case q.FromMember != "" && q.FromLonLat:
+		return nil, errors.New("redigo: GeoSearchQuery FromMember and FromLonLat are mutually exclusive")
+	case q.FromMember != "":
+		args = append(args, "FROMMEMBER", q.FromMember)
+	case q.FromLonLat:
+		args = append(args, "FROMLONLAT", q.Longitude, q.Latitude)
+	default:
+		return nil, errors.New("redigo: GeoSearchQuery requires FromMember or FromLonLat")
+	}
+
+	unit := q.Unit
+	if unit == "" {
+		unit = "m"
+	}
+	box := q.Width != 0 || q.Height != 0
+	switch {
+	case q.Radius != 0 && box:
+		return nil, errors.New("redigo: GeoSearchQuery Radius and Width and Height are mutually exclusive")
+	case q.Radius > 0:
+		args = append(args, "BYRADIUS", q.Radius, unit)
+	case q.Width > 0 && q.Height > 0:
+		args = append(args, "BYBOX", q.Width, q.Height, unit)
+	default:
+		return nil, errors.New("redigo: GeoSearchQuery requires a positive Radius or Width and Height")
+	}
+
+	switch q.Order {
+	case "":
+	case "ASC", "DESC":
+		args = append(args, q.Order)
+	default:
+		return nil, fmt.Errorf("redigo: invalid GeoSearchQuery Order %q", q.Order)
+	}
+
+	if q.Count > 0 {
+		args = append(args, "COUNT", q.Count)
+		if q.Any {
+			args = append(args, "ANY")
+		}
+	} else if q.Any {
+		return nil, errors.New("redigo: GeoSearchQuery Any requires Count")
+	}
+	return args, nil
+}
+
+// GeoSearchStore stores the members of the geospatial index src matching q
+// in dst using the GEOSEARCHSTORE command and returns the number of members
+// stored. By default, dst is a geospatial index with the positions of the
+// members. If storeDist is set, then dst is a sorted set with the distances
+// from the center in the unit of q as the scores.
+func GeoSearchStore(c Conn, dst, src string, q GeoSearchQuery, storeDist bool) (int64, error) {
+	args, err := q.args()
+	if err != nil {
+		return 0, err
+	}
+	args = append([]interface{}{dst, src}, args...)
+	if storeDist {
+		args = append(args, "STOREDIST")
+	}
+	return Int64(c.Do("GEOSEARCHSTORE", args...))
+}
+
+// Ping sends the PING command, verifies that the reply is PONG and returns
+// the round trip time of the command.
+func Ping(c Conn) (time.Duration, error) {
+	start := time.Now()
+	reply, err := c.Do("PING")
+	rtt := time.Since(start)
+	if err := StatusReply(reply, err, "PONG"); err != nil {
+		return 0, err
+	}
+	return rtt, nil
+}
+
+// PingMessage sends the PING command with msg, verifies that the reply echoes
+// msg and returns the round trip time of the command. A reply that does not
+// match msg indicates that the connection is out of sync with the server.
+func PingMessage(c Conn, msg string) (time.Duration, error) {
+	start := time.Now()
+	reply, err := Bytes(c.Do("PING", msg))
+	rtt := time.Since(start)
+	if err != nil {
+		return 0, err
+	}
+	if string(reply) != msg {
+		return 0, fmt.Errorf("redigo: PING reply %q does not match message %q", reply, msg)
+	}
+	return rtt, nil
+}
+
+// Append appends value to the string value of key using the APPEND command
+// and returns the length of the string after the command. The value is sent
+// as is, so binary data is appended exactly.
+func Append(c Conn, key string, value []byte) (int64, error) {
+	return Int64(c.Do("APPEND", key, value))
+}
+
+// StrLen returns the length of the string value of key using the STRLEN
+// command. StrLen returns 0, not ErrNil, when the key does not exist.
+func StrLen(c Conn, key string) (int64, error) {
+	return Int64(c.Do("STRLEN", key))
+}
+
+// ClusterCountKeysInSlot returns the number of keys in the hash slot of the
+// connected cluster node using the CLUSTER COUNTKEYSINSLOT command.
+func ClusterCountKeysInSlot(c Conn, slot int) (int64, error) {
+	return Int64(c.Do("CLUSTER", "COUNTKEYSINSLOT", slot))
+}
+
+// ClusterGetKeysInSlot returns up to count keys in the hash slot of the
+// connected cluster node using the CLUSTER GETKEYSINSLOT command.
+func ClusterGetKeysInSlot(c Conn, slot, count int) ([]string, error) {
+	return Strings(c.Do("CLUSTER", "GETKEYSINSLOT", slot, count))
+}
+
+// SetEx sets key to value with the time to live ttl using the SET command
+// with the EX option for a whole number of seconds and the PX option
+// otherwise. The SET options replace the SETEX and PSETEX commands. SetEx
+// returns an error if ttl is less than a millisecond.
+func SetEx(c Conn, key string, value []byte, ttl time.Duration) error {
+	if ttl < time.Millisecond {
+		return fmt.Errorf("redigo: SetEx requires a TTL of at least one millisecond, got %v", ttl)
+	}
+	var reply interface{}
+	var err error
+	if ttl%time.Second == 0 {
+		reply, err = c.Do("SET", key, value, "EX", int64(ttl/time.Second))
+	} else {
+		reply, err = c.Do("SET", key, value, "PX", int64(ttl/time.Millisecond))
+	}
+	return StatusReply(reply, err, "OK")
+}
+
+// GetSet sets key to value and returns the previous value using the GETSET
+// command. If the key did not exist, then GetSet returns ErrNil. GETSET is
+// deprecated as of Redis 6.2 in favor of SET with the GET option, which
+// returns the same reply. GetSet uses GETSET to support older servers.
+func GetSet(c Conn, key string, value []byte) ([]byte, error) {
+	return Bytes(c.Do("GETSET", key, value))
+}
+
+// TypeMismatchError is returned by DoChecked when the value stored at the key
+// does not have the expected type.
+type TypeMismatchError struct {
+	Command  string
+	Key      string
+	Expected RedisType
+	Actual   RedisType
+}
+
+func (e *TypeMismatchError) Error() string {
+	return fmt.Sprintf("redigo: %s on key %q requires type %s, key holds type %s", e.Command, e.Key, e.Expected, e.Actual)
+}
+
+// DoChecked checks the type of the first key with the TYPE command before
+// sending the command with args to the server. The first argument in args
+// must be the key. If the key holds a value of a type other than expected,
+// then DoChecked returns a *TypeMismatchError without sending the command. A
+// missing key passes the check because commands create the key as needed.
+//
+// DoChecked adds a round trip to the server for every command and the type
+// can change between the two commands. Use DoChecked in defensive code paths
+// where a clear error is worth the cost of the extra round trip, not as a
+// replacement for handling WRONGTYPE errors.
+func DoChecked(c Conn, expected RedisType, commandName string, args ...interface{}) (interface{}, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("redigo: DoChecked requires a key for %s", commandName)
+	}
+	actual, err := KeyType(c.Do("TYPE", args[0]))
+	if err != nil {
+		return nil, err
+	}
+	if actual != RedisTypeNone && actual != expected {
+		e := &TypeMismatchError{Command: commandName, Expected: expected, Actual: actual}
+		switch k := args[0].(type) {
+		case string:
+			e.Key = k
+		case []byte:
+			e.Key = string(k)
+		default:
+			e.Key = fmt.Sprint(k)
+		}
+		return nil, e
+	}
+	return c.Do(commandName, args...)
+}
+
+// LRangeEach calls fn with each element of the list stored at key in the
+// range from start to stop using the LRANGE command. The elements are passed
+// to fn as they are read from the connection instead of materializing the
+// reply, so memory use is bounded when processing large lists. The value
+// passed to fn is valid only until fn returns. If fn returns an error, then
+// LRangeEach returns the error. The index passed to fn is relative to start.
+func LRangeEach(c Conn, key string, start, stop int, fn func(index int, value []byte) error) error {
+	return DoEach(c, fn, "LRANGE", key, start, stop)
+}
+
+var (
+	// ErrAOFDisabled is returned by WaitDurable when local acknowledgments
+	// are requested and the server does not have AOF enabled.
+	ErrAOFDisabled = errors.New("redigo: WAITAOF requires appendonly to be enabled on the server")
+
+	// ErrDurabilityTimeout is returned by WaitDurable when the timeout
+	// elapses before the requested number of acknowledgments.
+	ErrDurabilityTimeout = errors.New("redigo: WAITAOF timed out before the requested acknowledgments")
+)
+
+// WaitDurable blocks until the writes sent on the connection are fsynced to
+// the AOF of the local server and of numReplicas replicas using the WAITAOF
+// command, which requires Redis 7.2. The numLocal argument is 0 or 1.
+// WaitDurable returns the number of replicas and local servers that
+// acknowledged the writes. If fewer than requested acknowledged the writes
+// before timeout, then WaitDurable returns the counts with
+// ErrDurabilityTimeout. A zero timeout blocks indefinitely. WaitDurable
+// returns ErrAOFDisabled when numLocal is 1 and the server does not have AOF
+// enabled.
+func WaitDurable(c Conn, numReplicas int, numLocal int, timeout time.Duration) (replicas int, local int, err error) {
+	counts, err := Ints(c.Do("WAITAOF", numLocal, numReplicas, int64(timeout/time.Millisecond)))
+	if err != nil {
+		var e Error
+		if errors.As(err, &e) && strings.Contains(string(e), "appendonly") {
+			return 0, 0, ErrAOFDisabled
+		}
+		return 0, 0, err
+	}
+	if len(counts) != 2 {
+		return 0, 0, fmt.Errorf("redigo: WAITAOF returned %d values, expected 2", len(counts))
+	}
+	local, replicas = counts[0], counts[1]
+	if local < numLocal || replicas < numReplicas {
+		return replicas, local, ErrDurabilityTimeout
+	}
+	return replicas, local, nil
+}
+
+// Command sends the command in parts to the server and returns the reply.
+// The first part is the command name. Unlike Do, Command splits a
+// multi-word command name such as "DEBUG SET-ACTIVE-EXPIRE" on spaces into
+// the command and its subcommand arguments. Do sends the name as a single
+// argument, which the server rejects as an unknown command, so multi-word
+// commands must be split when calling Do. The parts after the first are
+// sent as is. Use Command with StatusReply for the many commands that reply
+// with +OK:
+//
+//	reply, err := redis.Command(c, "DEBUG SET-ACTIVE-EXPIRE", 0)
+//	err = redis.StatusReply(reply, err, "OK")
+func Command(c Conn, parts ...interface{}) (interface{}, error) {
+	if len(parts) == 0 {
+		return nil, errors.New("redigo: Command requires a command name")
+	}
+	name, ok := parts[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("redigo: Command requires a string command name, got type %T", parts[0])
+	}
+	words := strings.Fields(name)
+	if len(words) == 0 {
+		return nil, errors.New("redigo: Command requires a command name")
+	}
+	args := make([]interface{}, 0, len(words)-1+len(parts)-1)
+	for _, w := range words[1:] {
+		args = append(args, w)
+	}
+	args = append(args, parts[1:]...)
+	return c.Do(words[0], args...)
+}
+
+// ClientSetName sets the name of the connection using the CLIENT SETNAME
+// command. An empty name removes the name. The server rejects names that
+// contain spaces, newlines or other characters outside of the printable
+// ASCII range, so ClientSetName checks the name and returns an error
+// without sending the command for an invalid name.
+func ClientSetName(c Conn, name string) error {
+	for i := 0; i < len(name); i++ {
+		if b := name[i]; b < '!' || b > '~' {
+			return fmt.Errorf("redigo: client name %q contains invalid character %q", name, b)
+		}
+	}
+	reply, err := c.Do("CLIENT", "SETNAME", name)
+	return StatusReply(reply, err, "OK")
+}
+
+// ClientGetName returns the name of the connection using the CLIENT GETNAME
+// command. ClientGetName returns an empty string, not ErrNil, when the
+// connection does not have a name.
+func ClientGetName(c Conn) (string, error) {
+	name, err := String(c.Do("CLIENT", "GETNAME"))
+	if err == ErrNil {
+		return "", nil
+	}
+	return name, err
+}
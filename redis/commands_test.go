@@ -0,0 +1,1014 @@
+// Copyright 2012 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package redis_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInterCard(t *testing.T) {
+	tests := []struct {
+		name        string
+		commandName string
+		keys        []string
+		limit       int
+		w           string
+	}{
+		{"sintercard", "SINTERCARD", []string{"a", "b"}, 0, "*4\r\n$10\r\nSINTERCARD\r\n$1\r\n2\r\n$1\r\na\r\n$1\r\nb\r\n"},
+		{"zintercard limit", "ZINTERCARD", []string{"a"}, 5, "*5\r\n$10\r\nZINTERCARD\r\n$1\r\n1\r\n$1\r\na\r\n$5\r\nLIMIT\r\n$1\r\n5\r\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			c, err := redis.Dial("", "", dialTestConn(":3\r\n", &buf))
+			require.NoError(t, err)
+			n, err := redis.InterCard(c, tt.commandName, tt.keys, tt.limit)
+			require.NoError(t, err)
+			require.Equal(t, int64(3), n)
+			require.Equal(t, tt.w, buf.String())
+		})
+	}
+}
+
+func TestMGetChunked(t *testing.T) {
+	var buf bytes.Buffer
+	c, err := redis.Dial("", "", dialTestConn("*2\r\n$1\r\n1\r\n$-1\r\n*1\r\n$1\r\n3\r\n", &buf))
+	require.NoError(t, err)
+
+	values, err := redis.MGetChunked(c, []string{"a", "b", "c"}, 2)
+	require.NoError(t, err)
+	require.Equal(t, [][]byte{[]byte("1"), nil, []byte("3")}, values)
+	require.Equal(t, "*3\r\n$4\r\nMGET\r\n$1\r\na\r\n$1\r\nb\r\n*2\r\n$4\r\nMGET\r\n$1\r\nc\r\n", buf.String())
+}
+
+func TestGetEx(t *testing.T) {
+	var buf bytes.Buffer
+	c, err := redis.Dial("", "", dialTestConn("$1\r\nv\r\n$-1\r\n", &buf))
+	require.NoError(t, err)
+
+	v, err := redis.GetEx(c, "k", redis.GetExOptions{PX: 1500 * time.Millisecond})
+	require.NoError(t, err)
+	require.Equal(t, []byte("v"), v)
+	require.Equal(t, "*4\r\n$5\r\nGETEX\r\n$1\r\nk\r\n$2\r\nPX\r\n$4\r\n1500\r\n", buf.String())
+
+	_, err = redis.GetEx(c, "k", redis.GetExOptions{})
+	require.Equal(t, redis.ErrNil, err)
+
+	buf.Reset()
+	for _, opts := range []redis.GetExOptions{
+		{EX: time.Second, PX: time.Second},
+		{EXAT: time.Now(), Persist: true},
+		{EX: 500 * time.Millisecond},
+		{EX: -time.Second},
+		{PX: time.Microsecond},
+	} {
+		_, err = redis.GetEx(c, "k", opts)
+		require.Error(t, err, "%+v", opts)
+	}
+	require.Empty(t, buf.String())
+}
+
+func TestGetWithTTL(t *testing.T) {
+	var buf bytes.Buffer
+	c, err := redis.Dial("", "", dialTestConn("*2\r\n$1\r\nv\r\n:1500\r\n*2\r\n$1\r\nv\r\n:-1\r\n*2\r\n$-1\r\n:-2\r\n", &buf))
+	require.NoError(t, err)
+
+	v, ttl, err := redis.GetWithTTL(c, "k")
+	require.NoError(t, err)
+	require.Equal(t, []byte("v"), v)
+	require.Equal(t, 1500*time.Millisecond, ttl)
+	require.True(t, strings.HasPrefix(buf.String(), "*4\r\n$7\r\nEVALSHA\r\n"), "got %q", buf.String())
+
+	_, ttl, err = redis.GetWithTTL(c, "k")
+	require.NoError(t, err)
+	require.Equal(t, redis.NoExpiry, ttl)
+
+	_, _, err = redis.GetWithTTL(c, "k")
+	require.Equal(t, redis.ErrNil, err)
+}
+
+func TestMoveWithExpiry(t *testing.T) {
+	var buf bytes.Buffer
+	c, err := redis.Dial("", "", dialTestConn("-NOSCRIPT No matching script.\r\n:1\r\n:0\r\n", &buf))
+	require.NoError(t, err)
+
+	ok, err := redis.MoveWithExpiry(c, "staged", "live", 1500*time.Millisecond)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	// The rename and the expiration are sent as a single script.
+	rc := redis.NewConn(&testConn{Reader: strings.NewReader(buf.String()), Writer: io.Discard}, 0, 0)
+	cmd, err := redis.ByteSlices(rc.Receive())
+	require.NoError(t, err)
+	require.Equal(t, "EVALSHA", string(cmd[0]))
+	cmd, err = redis.ByteSlices(rc.Receive())
+	require.NoError(t, err)
+	require.Equal(t, "EVAL", string(cmd[0]))
+	require.Contains(t, string(cmd[1]), "RENAME")
+	require.Contains(t, string(cmd[1]), "PEXPIRE")
+	require.Equal(t, []string{"2", "staged", "live", "1500"}, []string{string(cmd[2]), string(cmd[3]), string(cmd[4]), string(cmd[5])})
+
+	ok, err = redis.MoveWithExpiry(c, "staged", "live", 0)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	// A TTL that would persist dst instead of expiring it is rejected without
+	// sending a command.
+	buf.Reset()
+	for _, ttl := range []time.Duration{-time.Second, time.Microsecond} {
+		_, err = redis.MoveWithExpiry(c, "staged", "live", ttl)
+		require.Error(t, err, ttl)
+	}
+	require.Empty(t, buf.String())
+}
+
+func TestXAck(t *testing.T) {
+	var buf bytes.Buffer
+	c, err := redis.Dial("", "", dialTestConn(":2\r\n*2\r\n:1\r\n:-1\r\n", &buf))
+	require.NoError(t, err)
+
+	n, err := redis.XAck(c, "s", "g", "1-0", "2-0")
+	require.NoError(t, err)
+	require.Equal(t, int64(2), n)
+	require.Equal(t, "*5\r\n$4\r\nXACK\r\n$1\r\ns\r\n$1\r\ng\r\n$3\r\n1-0\r\n$3\r\n2-0\r\n", buf.String())
+
+	buf.Reset()
+	codes, err := redis.XAckDel(c, "s", "g", "ACKED", "1-0", "3-0")
+	require.NoError(t, err)
+	require.Equal(t, []int64{1, -1}, codes)
+	require.Equal(t, "*8\r\n$7\r\nXACKDEL\r\n$1\r\ns\r\n$1\r\ng\r\n$5\r\nACKED\r\n$3\r\nIDS\r\n$1\r\n2\r\n$3\r\n1-0\r\n$3\r\n3-0\r\n", buf.String())
+}
+
+func TestObjectFreq(t *testing.T) {
+	c, err := redis.Dial("", "", dialTestConn(":7\r\n-ERR An LFU maxmemory policy is not selected, access frequency not tracked.\r\n", io.Discard))
+	require.NoError(t, err)
+
+	n, err := redis.ObjectFreq(c, "k")
+	require.NoError(t, err)
+	require.Equal(t, int64(7), n)
+
+	_, err = redis.ObjectFreq(c, "k")
+	require.ErrorIs(t, err, redis.ErrLFUNotEnabled)
+	require.Contains(t, err.Error(), "access frequency not tracked")
+	var e redis.Error
+	require.ErrorAs(t, err, &e)
+	require.Equal(t, "ERR An LFU maxmemory policy is not selected, access frequency not tracked.", string(e))
+}
+
+func TestExpire(t *testing.T) {
+	tests := []struct {
+		name string
+		cond redis.ExpireCond
+		w    string
+	}{
+		{"always", redis.ExpireAlways, "*3\r\n$6\r\nEXPIRE\r\n$1\r\nk\r\n$2\r\n10\r\n"},
+		{"nx", redis.ExpireNX, "*4\r\n$6\r\nEXPIRE\r\n$1\r\nk\r\n$2\r\n10\r\n$2\r\nNX\r\n"},
+		{"xx", redis.ExpireXX, "*4\r\n$6\r\nEXPIRE\r\n$1\r\nk\r\n$2\r\n10\r\n$2\r\nXX\r\n"},
+		{"gt", redis.ExpireGT, "*4\r\n$6\r\nEXPIRE\r\n$1\r\nk\r\n$2\r\n10\r\n$2\r\nGT\r\n"},
+		{"lt", redis.ExpireLT, "*4\r\n$6\r\nEXPIRE\r\n$1\r\nk\r\n$2\r\n10\r\n$2\r\nLT\r\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			c, err := redis.Dial("", "", dialTestConn(":1\r\n", &buf))
+			require.NoError(t, err)
+			ok, err := redis.Expire(c, "k", 10*time.Second, tt.cond)
+			require.NoError(t, err)
+			require.True(t, ok)
+			require.Equal(t, tt.w, buf.String())
+		})
+	}
+
+	c, err := redis.Dial("", "", dialTestConn("-ERR wrong number of arguments for 'expire' command\r\n", io.Discard))
+	require.NoError(t, err)
+	_, err = redis.Expire(c, "k", time.Second, redis.ExpireNX)
+	require.EqualError(t, err, "redigo: EXPIRE NX requires Redis 7.0 or later: ERR wrong number of arguments for 'expire' command")
+
+	// A TTL that is not a whole number of seconds uses PEXPIRE.
+	var buf bytes.Buffer
+	c, err = redis.Dial("", "", dialTestConn(":1\r\n", &buf))
+	require.NoError(t, err)
+	ok, err := redis.Expire(c, "k", 1500*time.Millisecond, redis.ExpireAlways)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, "*3\r\n$7\r\nPEXPIRE\r\n$1\r\nk\r\n$4\r\n1500\r\n", buf.String())
+
+	// A TTL that would delete the key is rejected without sending a command.
+	buf.Reset()
+	for _, ttl := range []time.Duration{0, time.Microsecond, -time.Second} {
+		_, err = redis.Expire(c, "k", ttl, redis.ExpireAlways)
+		require.Error(t, err, ttl)
+	}
+	require.Equal(t, "", buf.String())
+}
+
+func TestBitCount(t *testing.T) {
+	tests := []struct {
+		name string
+		r    *redis.BitCountRange
+		w    string
+	}{
+		{"all", nil, "*2\r\n$8\r\nBITCOUNT\r\n$1\r\nk\r\n"},
+		{"byte", &redis.BitCountRange{Start: 1, End: -1}, "*4\r\n$8\r\nBITCOUNT\r\n$1\r\nk\r\n$1\r\n1\r\n$2\r\n-1\r\n"},
+		{"bit", &redis.BitCountRange{Start: 5, End: 30, Unit: redis.BitCountBit}, "*5\r\n$8\r\nBITCOUNT\r\n$1\r\nk\r\n$1\r\n5\r\n$2\r\n30\r\n$3\r\nBIT\r\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			c, err := redis.Dial("", "", dialTestConn(":4\r\n", &buf))
+			require.NoError(t, err)
+			n, err := redis.BitCount(c, "k", tt.r)
+			require.NoError(t, err)
+			require.Equal(t, int64(4), n)
+			require.Equal(t, tt.w, buf.String())
+		})
+	}
+
+	c, err := redis.Dial("", "", dialTestConn("-ERR syntax error\r\n", io.Discard))
+	require.NoError(t, err)
+	_, err = redis.BitCount(c, "k", &redis.BitCountRange{End: 7, Unit: redis.BitCountBit})
+	require.EqualError(t, err, "redigo: BITCOUNT BIT requires Redis 7.0 or later: ERR syntax error")
+}
+
+func TestHExpire(t *testing.T) {
+	var buf bytes.Buffer
+	c, err := redis.Dial("", "", dialTestConn("*2\r\n:1\r\n:-2\r\n*3\r\n:60\r\n:-2\r\n:-1\r\n", &buf))
+	require.NoError(t, err)
+
+	results, err := redis.HExpire(c, "h", time.Minute, "a", "missing")
+	require.NoError(t, err)
+	require.Equal(t, []int{redis.HExpireSet, redis.HExpireNoField}, results)
+	require.Equal(t, "*7\r\n$7\r\nHEXPIRE\r\n$1\r\nh\r\n$2\r\n60\r\n$6\r\nFIELDS\r\n$1\r\n2\r\n$1\r\na\r\n$7\r\nmissing\r\n", buf.String())
+
+	buf.Reset()
+	ttls, err := redis.HTTL(c, "h", "a", "missing", "b")
+	require.NoError(t, err)
+	require.Equal(t, []time.Duration{time.Minute, redis.NoField, redis.NoExpiry}, ttls)
+	require.Equal(t, "*7\r\n$4\r\nHTTL\r\n$1\r\nh\r\n$6\r\nFIELDS\r\n$1\r\n3\r\n$1\r\na\r\n$7\r\nmissing\r\n$1\r\nb\r\n", buf.String())
+
+	// A TTL that is not a whole number of seconds uses HPEXPIRE.
+	buf.Reset()
+	c, err = redis.Dial("", "", dialTestConn("*1\r\n:1\r\n", &buf))
+	require.NoError(t, err)
+	results, err = redis.HExpire(c, "h", 250*time.Millisecond, "a")
+	require.NoError(t, err)
+	require.Equal(t, []int{redis.HExpireSet}, results)
+	require.Equal(t, "*6\r\n$8\r\nHPEXPIRE\r\n$1\r\nh\r\n$3\r\n250\r\n$6\r\nFIELDS\r\n$1\r\n1\r\n$1\r\na\r\n", buf.String())
+
+	// A TTL that would delete the fields is rejected without sending a command.
+	buf.Reset()
+	for _, ttl := range []time.Duration{0, time.Microsecond, -time.Second} {
+		_, err = redis.HExpire(c, "h", ttl, "a")
+		require.Error(t, err, ttl)
+	}
+	require.Equal(t, "", buf.String())
+}
+
+func TestZPop(t *testing.T) {
+	tests := []struct {
+		name  string
+		min   bool
+		count int
+		r     string
+		w     string
+		want  []redis.ZMember
+	}{
+		{"single", true, 0, "*2\r\n$1\r\na\r\n$1\r\n1\r\n", "*2\r\n$7\r\nZPOPMIN\r\n$1\r\nz\r\n", []redis.ZMember{{"a", 1}}},
+		{"multi", false, 2, "*4\r\n$1\r\nc\r\n$3\r\n3.5\r\n$1\r\nb\r\n$1\r\n2\r\n", "*3\r\n$7\r\nZPOPMAX\r\n$1\r\nz\r\n$1\r\n2\r\n", []redis.ZMember{{"c", 3.5}, {"b", 2}}},
+		{"empty", true, 2, "*0\r\n", "*3\r\n$7\r\nZPOPMIN\r\n$1\r\nz\r\n$1\r\n2\r\n", []redis.ZMember{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			c, err := redis.Dial("", "", dialTestConn(tt.r, &buf))
+			require.NoError(t, err)
+			members, err := redis.ZPop(c, "z", tt.min, tt.count)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, members)
+			require.Equal(t, tt.w, buf.String())
+		})
+	}
+
+	_, err := redis.ZPopResults(nil, nil)
+	require.ErrorIs(t, err, redis.ErrNil)
+}
+
+func TestReplicationInfo(t *testing.T) {
+	info := "# Replication\r\n" +
+		"role:master\r\n" +
+		"connected_slaves:2\r\n" +
+		"slave0:ip=10.0.0.2,port=6380,state=online,offset=9523,lag=0\r\n" +
+		"slave1:ip=10.0.0.3,port=6381,state=wait_bgsave,offset=0,lag=1\r\n" +
+		"master_failover_state:no-failover\r\n" +
+		"master_replid:6b0c8e3a3d1f7c5e2a9b4d8f1e6c3a7b5d2e9f40\r\n" +
+		"master_replid2:0000000000000000000000000000000000000000\r\n" +
+		"master_repl_offset:9523\r\n" +
+		"second_repl_offset:-1\r\n" +
+		"repl_backlog_active:1\r\n" +
+		"repl_backlog_size:1048576\r\n" +
+		"repl_backlog_first_byte_offset:1\r\n" +
+		"repl_backlog_histlen:9523\r\n"
+	var buf bytes.Buffer
+	c, err := redis.Dial("", "", dialTestConn("$"+strconv.Itoa(len(info))+"\r\n"+info+"\r\n", &buf))
+	require.NoError(t, err)
+
+	status, err := redis.ReplicationInfo(c)
+	require.NoError(t, err)
+	require.Equal(t, redis.ReplicationStatus{
+		Role:             "master",
+		ConnectedSlaves:  2,
+		MasterReplOffset: 9523,
+		Slaves: []redis.SlaveInfo{
+			{IP: "10.0.0.2", Port: 6380, State: "online", Offset: 9523, Lag: 0},
+			{IP: "10.0.0.3", Port: 6381, State: "wait_bgsave", Offset: 0, Lag: 1},
+		},
+	}, status)
+	require.Equal(t, "*2\r\n$4\r\nINFO\r\n$11\r\nreplication\r\n", buf.String())
+}
+
+func TestHSet(t *testing.T) {
+	type user struct {
+		Name string `redis:"name"`
+		Age  int    `redis:"age"`
+	}
+	tests := []struct {
+		name   string
+		fields interface{}
+		w      string
+	}{
+		{"map", map[string]interface{}{"name": "gopher"}, "*4\r\n$4\r\nHSET\r\n$1\r\nh\r\n$4\r\nname\r\n$6\r\ngopher\r\n"},
+		{"struct", user{Name: "gopher", Age: 13}, "*6\r\n$4\r\nHSET\r\n$1\r\nh\r\n$4\r\nname\r\n$6\r\ngopher\r\n$3\r\nage\r\n$2\r\n13\r\n"},
+		{"struct pointer", &user{Name: "gopher", Age: 13}, "*6\r\n$4\r\nHSET\r\n$1\r\nh\r\n$4\r\nname\r\n$6\r\ngopher\r\n$3\r\nage\r\n$2\r\n13\r\n"},
+		{"slice", []interface{}{"name", "gopher"}, "*4\r\n$4\r\nHSET\r\n$1\r\nh\r\n$4\r\nname\r\n$6\r\ngopher\r\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			c, err := redis.Dial("", "", dialTestConn(":1\r\n", &buf))
+			require.NoError(t, err)
+			n, err := redis.HSet(c, "h", tt.fields)
+			require.NoError(t, err)
+			require.Equal(t, int64(1), n)
+			require.Equal(t, tt.w, buf.String())
+		})
+	}
+
+	c, err := redis.Dial("", "", dialTestConn("", io.Discard))
+	require.NoError(t, err)
+	_, err = redis.HSet(c, "h", []interface{}{"name"})
+	require.Error(t, err)
+	_, err = redis.HSet(c, "h", map[string]interface{}{})
+	require.Error(t, err)
+}
+
+func TestXAdd(t *testing.T) {
+	tests := []struct {
+		name   string
+		id     string
+		fields interface{}
+		r      string
+		w      string
+	}{
+		{"auto", "*", []string{"f", "v"}, "$15\r\n1526919030474-0\r\n", "*5\r\n$4\r\nXADD\r\n$1\r\ns\r\n$1\r\n*\r\n$1\r\nf\r\n$1\r\nv\r\n"},
+		{"explicit", "5-1", map[string]int{"n": 1}, "$3\r\n5-1\r\n", "*5\r\n$4\r\nXADD\r\n$1\r\ns\r\n$3\r\n5-1\r\n$1\r\nn\r\n$1\r\n1\r\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			c, err := redis.Dial("", "", dialTestConn(tt.r, &buf))
+			require.NoError(t, err)
+			id, err := redis.XAdd(c, "s", tt.id, tt.fields)
+			require.NoError(t, err)
+			require.Equal(t, tt.w, buf.String())
+			_, _, err = redis.XParseID(id)
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestXParseID(t *testing.T) {
+	ms, seq, err := redis.XParseID("1526919030474-55")
+	require.NoError(t, err)
+	require.Equal(t, int64(1526919030474), ms)
+	require.Equal(t, int64(55), seq)
+
+	ms, seq, err = redis.XParseID("7")
+	require.NoError(t, err)
+	require.Equal(t, int64(7), ms)
+	require.Equal(t, int64(0), seq)
+
+	for _, id := range []string{"", "*", "1-x", "-1", "1--2"} {
+		_, _, err = redis.XParseID(id)
+		require.Error(t, err, id)
+	}
+}
+
+func TestGetRange(t *testing.T) {
+	var buf bytes.Buffer
+	c, err := redis.Dial("", "", dialTestConn(":8\r\n$3\r\n\x00\xff\r\r\n$0\r\n\r\n", &buf))
+	require.NoError(t, err)
+
+	n, err := redis.SetRange(c, "k", 5, []byte{0x00, 0xff, '\r'})
+	require.NoError(t, err)
+	require.Equal(t, int64(8), n)
+	require.Equal(t, "*4\r\n$8\r\nSETRANGE\r\n$1\r\nk\r\n$1\r\n5\r\n$3\r\n\x00\xff\r\r\n", buf.String())
+
+	buf.Reset()
+	p, err := redis.GetRange(c, "k", 5, -1)
+	require.NoError(t, err)
+	require.Equal(t, []byte{0x00, 0xff, '\r'}, p)
+	require.Equal(t, "*4\r\n$8\r\nGETRANGE\r\n$1\r\nk\r\n$1\r\n5\r\n$2\r\n-1\r\n", buf.String())
+
+	p, err = redis.GetRange(c, "missing", 0, 10)
+	require.NoError(t, err)
+	require.NotNil(t, p)
+	require.Empty(t, p)
+}
+
+func TestGetSetBit(t *testing.T) {
+	var buf bytes.Buffer
+	c, err := redis.Dial("", "", dialTestConn(":0\r\n:1\r\n:1\r\n", &buf))
+	require.NoError(t, err)
+
+	old, err := redis.SetBit(c, "k", 7, 1)
+	require.NoError(t, err)
+	require.Equal(t, 0, old)
+	require.Equal(t, "*4\r\n$6\r\nSETBIT\r\n$1\r\nk\r\n$1\r\n7\r\n$1\r\n1\r\n", buf.String())
+
+	old, err = redis.SetBit(c, "k", 7, 0)
+	require.NoError(t, err)
+	require.Equal(t, 1, old)
+
+	buf.Reset()
+	bit, err := redis.GetBit(c, "k", 100)
+	require.NoError(t, err)
+	require.Equal(t, 1, bit)
+	require.Equal(t, "*3\r\n$6\r\nGETBIT\r\n$1\r\nk\r\n$3\r\n100\r\n", buf.String())
+
+	// Invalid arguments are rejected without sending the command.
+	buf.Reset()
+	for _, v := range []int{-1, 2, 255} {
+		_, err = redis.SetBit(c, "k", 0, v)
+		require.EqualError(t, err, "redigo: SetBit requires a bit value of 0 or 1, got "+strconv.Itoa(v))
+	}
+	_, err = redis.SetBit(c, "k", -1, 1)
+	require.Error(t, err)
+	_, err = redis.GetBit(c, "k", -1)
+	require.Error(t, err)
+	require.Equal(t, "", buf.String())
+}
+
+func TestBitPos(t *testing.T) {
+	tests := []struct {
+		name string
+		r    *redis.BitPosRange
+		resp string
+		w    string
+		want int64
+		err  error
+	}{
+		{"all", nil, ":12\r\n", "*3\r\n$6\r\nBITPOS\r\n$1\r\nk\r\n$1\r\n1\r\n", 12, nil},
+		{"byte", &redis.BitPosRange{Start: 2, End: -1}, ":16\r\n", "*5\r\n$6\r\nBITPOS\r\n$1\r\nk\r\n$1\r\n1\r\n$1\r\n2\r\n$2\r\n-1\r\n", 16, nil},
+		{"byte not found", &redis.BitPosRange{Start: 2, End: -1}, ":-1\r\n", "*5\r\n$6\r\nBITPOS\r\n$1\r\nk\r\n$1\r\n1\r\n$1\r\n2\r\n$2\r\n-1\r\n", 0, redis.ErrNil},
+		{"bit", &redis.BitPosRange{Start: 7, End: 15, Unit: redis.BitCountBit}, ":9\r\n", "*6\r\n$6\r\nBITPOS\r\n$1\r\nk\r\n$1\r\n1\r\n$1\r\n7\r\n$2\r\n15\r\n$3\r\nBIT\r\n", 9, nil},
+		{"bit not found", &redis.BitPosRange{Start: 7, End: 15, Unit: redis.BitCountBit}, ":-1\r\n", "*6\r\n$6\r\nBITPOS\r\n$1\r\nk\r\n$1\r\n1\r\n$1\r\n7\r\n$2\r\n15\r\n$3\r\nBIT\r\n", 0, redis.ErrNil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			c, err := redis.Dial("", "", dialTestConn(tt.resp, &buf))
+			require.NoError(t, err)
+			n, err := redis.BitPos(c, "k", 1, tt.r)
+			require.Equal(t, tt.err, err)
+			require.Equal(t, tt.want, n)
+			require.Equal(t, tt.w, buf.String())
+		})
+	}
+
+	c, err := redis.Dial("", "", dialTestConn("-ERR syntax error\r\n", io.Discard))
+	require.NoError(t, err)
+	_, err = redis.BitPos(c, "k", 0, &redis.BitPosRange{End: 7, Unit: redis.BitCountBit})
+	require.EqualError(t, err, "redigo: BITPOS BIT requires Redis 7.0 or later: ERR syntax error")
+}
+
+func TestClientKill(t *testing.T) {
+	tests := []struct {
+		name string
+		f    redis.ClientKillFilter
+		w    string
+	}{
+		{"id", redis.ClientKillFilter{ID: 42}, "*4\r\n$6\r\nCLIENT\r\n$4\r\nKILL\r\n$2\r\nID\r\n$2\r\n42\r\n"},
+		{"type skipme", redis.ClientKillFilter{Type: "pubsub", SkipMe: "no"}, "*6\r\n$6\r\nCLIENT\r\n$4\r\nKILL\r\n$4\r\nTYPE\r\n$6\r\npubsub\r\n$6\r\nSKIPME\r\n$2\r\nno\r\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			c, err := redis.Dial("", "", dialTestConn(":2\r\n", &buf))
+			require.NoError(t, err)
+			n, err := redis.ClientKill(c, tt.f)
+			require.NoError(t, err)
+			require.Equal(t, int64(2), n)
+			require.Equal(t, tt.w, buf.String())
+		})
+	}
+
+	c, err := redis.Dial("", "", dialTestConn("", io.Discard))
+	require.NoError(t, err)
+	_, err = redis.ClientKill(c, redis.ClientKillFilter{SkipMe: "yes"})
+	require.EqualError(t, err, "redigo: ClientKillFilter requires at least one filter")
+	_, err = redis.ClientKill(c, redis.ClientKillFilter{ID: 1, SkipMe: "maybe"})
+	require.Error(t, err)
+}
+
+func TestClientKillAddr(t *testing.T) {
+	c, err := redis.Dial("", "", dialTestConn("+OK\r\n-ERR No such client\r\n", io.Discard))
+	require.NoError(t, err)
+	ok, err := redis.ClientKillAddr(c, "127.0.0.1:6000")
+	require.NoError(t, err)
+	require.True(t, ok)
+	ok, err = redis.ClientKillAddr(c, "127.0.0.1:6000")
+	require.NoError(t, err)
+	require.False(t, ok)
+}
+
+func TestSPop(t *testing.T) {
+	var buf bytes.Buffer
+	c, err := redis.Dial("", "", dialTestConn("*2\r\n$1\r\na\r\n$1\r\nb\r\n*0\r\n*-1\r\n", &buf))
+	require.NoError(t, err)
+	members, err := redis.SPop(c, "s", 2)
+	require.NoError(t, err)
+	require.Equal(t, [][]byte{[]byte("a"), []byte("b")}, members)
+	require.Equal(t, "*3\r\n$4\r\nSPOP\r\n$1\r\ns\r\n$1\r\n2\r\n", buf.String())
+
+	for i := 0; i < 2; i++ {
+		members, err = redis.SPop(c, "s", 2)
+		require.NoError(t, err)
+		require.NotNil(t, members)
+		require.Empty(t, members)
+	}
+}
+
+func TestSPopOne(t *testing.T) {
+	var buf bytes.Buffer
+	c, err := redis.Dial("", "", dialTestConn("$1\r\na\r\n$-1\r\n", &buf))
+	require.NoError(t, err)
+	member, err := redis.SPopOne(c, "s")
+	require.NoError(t, err)
+	require.Equal(t, []byte("a"), member)
+	require.Equal(t, "*2\r\n$4\r\nSPOP\r\n$1\r\ns\r\n", buf.String())
+
+	_, err = redis.SPopOne(c, "s")
+	require.Equal(t, redis.ErrNil, err)
+}
+
+func TestBLMPop(t *testing.T) {
+	var buf bytes.Buffer
+	c, err := redis.Dial("", "", dialTestConn("*2\r\n$1\r\nb\r\n*1\r\n$1\r\nx\r\n*-1\r\n", &buf))
+	require.NoError(t, err)
+	key, values, err := redis.BLMPop(c, 1500*time.Millisecond, "LEFT", "a", "b")
+	require.NoError(t, err)
+	require.Equal(t, "b", key)
+	require.Equal(t, []string{"x"}, values)
+	require.Equal(t, "*6\r\n$6\r\nBLMPOP\r\n$3\r\n1.5\r\n$1\r\n2\r\n$1\r\na\r\n$1\r\nb\r\n$4\r\nLEFT\r\n", buf.String())
+
+	_, _, err = redis.BLMPop(c, time.Second, "RIGHT", "a")
+	require.Equal(t, redis.ErrTimeout, err)
+}
+
+func TestTTL(t *testing.T) {
+	tests := []struct {
+		name string
+		resp string
+		want time.Duration
+		err  error
+	}{
+		{"expiry", ":30\r\n", 30 * time.Second, nil},
+		{"no expiry", ":-1\r\n", 0, redis.ErrNoExpire},
+		{"missing", ":-2\r\n", 0, redis.ErrKeyMissing},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			c, err := redis.Dial("", "", dialTestConn(tt.resp, &buf))
+			require.NoError(t, err)
+			d, err := redis.TTL(c, "k")
+			require.Equal(t, tt.err, err)
+			require.Equal(t, tt.want, d)
+			require.Equal(t, "*2\r\n$3\r\nTTL\r\n$1\r\nk\r\n", buf.String())
+		})
+	}
+}
+
+func TestPTTL(t *testing.T) {
+	var buf bytes.Buffer
+	c, err := redis.Dial("", "", dialTestConn(":1500\r\n:-1\r\n:-2\r\n", &buf))
+	require.NoError(t, err)
+	d, err := redis.PTTL(c, "k")
+	require.NoError(t, err)
+	require.Equal(t, 1500*time.Millisecond, d)
+	require.Equal(t, "*2\r\n$4\r\nPTTL\r\n$1\r\nk\r\n", buf.String())
+
+	_, err = redis.PTTL(c, "k")
+	require.Equal(t, redis.ErrNoExpire, err)
+	_, err = redis.PTTL(c, "k")
+	require.Equal(t, redis.ErrKeyMissing, err)
+}
+
+func TestDelUnlink(t *testing.T) {
+	var buf bytes.Buffer
+	c, err := redis.Dial("", "", dialTestConn(":2\r\n:1\r\n", &buf))
+	require.NoError(t, err)
+	n, err := redis.Del(c, "a", "b")
+	require.NoError(t, err)
+	require.Equal(t, int64(2), n)
+	n, err = redis.Unlink(c, "a")
+	require.NoError(t, err)
+	require.Equal(t, int64(1), n)
+	require.Equal(t, "*3\r\n$3\r\nDEL\r\n$1\r\na\r\n$1\r\nb\r\n*2\r\n$6\r\nUNLINK\r\n$1\r\na\r\n", buf.String())
+}
+
+func TestDeleteMatching(t *testing.T) {
+	scan := func(cursor string) string {
+		return "*6\r\n$4\r\nSCAN\r\n$" + strconv.Itoa(len(cursor)) + "\r\n" + cursor + "\r\n$5\r\nMATCH\r\n$3\r\nk:*\r\n$5\r\nCOUNT\r\n$3\r\n100\r\n"
+	}
+	resp := "*2\r\n$2\r\n17\r\n*2\r\n$3\r\nk:a\r\n$3\r\nk:b\r\n" + ":2\r\n" +
+		"*2\r\n$1\r\n9\r\n*0\r\n" +
+		"*2\r\n$1\r\n0\r\n*1\r\n$3\r\nk:c\r\n" + ":1\r\n"
+
+	for _, unlink := range []bool{true, false} {
+		command := "*3\r\n$3\r\nDEL\r\n"
+		single := "*2\r\n$3\r\nDEL\r\n"
+		if unlink {
+			command = "*3\r\n$6\r\nUNLINK\r\n"
+			single = "*2\r\n$6\r\nUNLINK\r\n"
+		}
+		var buf bytes.Buffer
+		c, err := redis.Dial("", "", dialTestConn(resp, &buf))
+		require.NoError(t, err)
+		n, err := redis.DeleteMatching(c, "k:*", unlink)
+		require.NoError(t, err)
+		require.Equal(t, int64(3), n)
+		require.Equal(t, scan("0")+command+"$3\r\nk:a\r\n$3\r\nk:b\r\n"+
+			scan("17")+
+			scan("9")+single+"$3\r\nk:c\r\n", buf.String())
+	}
+}
+
+func TestGeoSearchStore(t *testing.T) {
+	tests := []struct {
+		name      string
+		q         redis.GeoSearchQuery
+		storeDist bool
+		w         string
+	}{
+		{
+			"radius",
+			redis.GeoSearchQuery{FromMember: "m", Radius: 5, Unit: "km", Order: "ASC", Count: 3},
+			false,
+			"*11\r\n$14\r\nGEOSEARCHSTORE\r\n$3\r\ndst\r\n$3\r\nsrc\r\n$10\r\nFROMMEMBER\r\n$1\r\nm\r\n$8\r\nBYRADIUS\r\n$1\r\n5\r\n$2\r\nkm\r\n$3\r\nASC\r\n$5\r\nCOUNT\r\n$1\r\n3\r\n",
+		},
+		{
+			"box",
+			redis.GeoSearchQuery{FromLonLat: true, Longitude: 13.5, Latitude: 52.25, Width: 400, Height: 200, Count: 2, Any: true},
+			true,
+			"*14\r\n$14\r\nGEOSEARCHSTORE\r\n$3\r\ndst\r\n$3\r\nsrc\r\n$10\r\nFROMLONLAT\r\n$4\r\n13.5\r\n$5\r\n52.25\r\n$5\r\nBYBOX\r\n$3\r\n400\r\n$3\r\n200\r\n$1\r\nm\r\n$5\r\nCOUNT\r\n$1\r\n2\r\n$3\r\nANY\r\n$9\r\nSTOREDIST\r\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			c, err := redis.Dial("", "", dialTestConn(":2\r\n", &buf))
+			require.NoError(t, err)
+			n, err := redis.GeoSearchStore(c, "dst", "src", tt.q, tt.storeDist)
+			require.NoError(t, err)
+			require.Equal(t, int64(2), n)
+			require.Equal(t, tt.w, buf.String())
+		})
+	}
+
+	c, err := redis.Dial("", "", dialTestConn("", io.Discard))
+	require.NoError(t, err)
+	for _, q := range []redis.GeoSearchQuery{
+		{Radius: 1},
+		{FromMember: "m", FromLonLat: true, Radius: 1},
+		{FromMember: "m"},
+		{FromMember: "m", Radius: 1, Width: 1, Height: 1},
+		{FromMember: "m", Radius: 1, Order: "asc"},
+		{FromMember: "m", Radius: 1, Any: true},
+	} {
+		_, err := redis.GeoSearchStore(c, "dst", "src", q, false)
+		require.Error(t, err, "%+v", q)
+	}
+}
+
+func TestPing(t *testing.T) {
+	var buf bytes.Buffer
+	c, err := redis.Dial("", "", dialTestConn("+PONG\r\n+OK\r\n", &buf))
+	require.NoError(t, err)
+	_, err = redis.Ping(c)
+	require.NoError(t, err)
+	require.Equal(t, "*1\r\n$4\r\nPING\r\n", buf.String())
+
+	_, err = redis.Ping(c)
+	require.EqualError(t, err, `redigo: unexpected status "OK", expected "PONG"`)
+}
+
+func TestPingMessage(t *testing.T) {
+	var buf bytes.Buffer
+	c, err := redis.Dial("", "", dialTestConn("$5\r\nhello\r\n$5\r\nhellx\r\n", &buf))
+	require.NoError(t, err)
+	_, err = redis.PingMessage(c, "hello")
+	require.NoError(t, err)
+	require.Equal(t, "*2\r\n$4\r\nPING\r\n$5\r\nhello\r\n", buf.String())
+
+	_, err = redis.PingMessage(c, "hello")
+	require.EqualError(t, err, `redigo: PING reply "hellx" does not match message "hello"`)
+}
+
+func TestAppendStrLen(t *testing.T) {
+	var buf bytes.Buffer
+	c, err := redis.Dial("", "", dialTestConn(":4\r\n:4\r\n:0\r\n", &buf))
+	require.NoError(t, err)
+	n, err := redis.Append(c, "k", []byte{0, '\r', '\n', 0xff})
+	require.NoError(t, err)
+	require.Equal(t, int64(4), n)
+	require.Equal(t, "*3\r\n$6\r\nAPPEND\r\n$1\r\nk\r\n$4\r\n\x00\r\n\xff\r\n", buf.String())
+
+	buf.Reset()
+	n, err = redis.StrLen(c, "k")
+	require.NoError(t, err)
+	require.Equal(t, int64(4), n)
+	require.Equal(t, "*2\r\n$6\r\nSTRLEN\r\n$1\r\nk\r\n", buf.String())
+
+	n, err = redis.StrLen(c, "missing")
+	require.NoError(t, err)
+	require.Equal(t, int64(0), n)
+}
+
+func TestClusterKeysInSlot(t *testing.T) {
+	var buf bytes.Buffer
+	c, err := redis.Dial("", "", dialTestConn(":7\r\n*2\r\n$1\r\na\r\n$1\r\nb\r\n", &buf))
+	require.NoError(t, err)
+	n, err := redis.ClusterCountKeysInSlot(c, 12182)
+	require.NoError(t, err)
+	require.Equal(t, int64(7), n)
+	require.Equal(t, "*3\r\n$7\r\nCLUSTER\r\n$15\r\nCOUNTKEYSINSLOT\r\n$5\r\n12182\r\n", buf.String())
+
+	buf.Reset()
+	keys, err := redis.ClusterGetKeysInSlot(c, 12182, 2)
+	require.NoError(t, err)
+	require.Equal(t, []string{"a", "b"}, keys)
+	require.Equal(t, "*4\r\n$7\r\nCLUSTER\r\n$13\r\nGETKEYSINSLOT\r\n$5\r\n12182\r\n$1\r\n2\r\n", buf.String())
+}
+
+func TestSetEx(t *testing.T) {
+	tests := []struct {
+		name string
+		ttl  time.Duration
+		w    string
+	}{
+		{"seconds", 2 * time.Second, "*5\r\n$3\r\nSET\r\n$1\r\nk\r\n$1\r\nv\r\n$2\r\nEX\r\n$1\r\n2\r\n"},
+		{"sub-second", 250 * time.Millisecond, "*5\r\n$3\r\nSET\r\n$1\r\nk\r\n$1\r\nv\r\n$2\r\nPX\r\n$3\r\n250\r\n"},
+		{"fractional seconds", 1500 * time.Millisecond, "*5\r\n$3\r\nSET\r\n$1\r\nk\r\n$1\r\nv\r\n$2\r\nPX\r\n$4\r\n1500\r\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			c, err := redis.Dial("", "", dialTestConn("+OK\r\n", &buf))
+			require.NoError(t, err)
+			require.NoError(t, redis.SetEx(c, "k", []byte("v"), tt.ttl))
+			require.Equal(t, tt.w, buf.String())
+		})
+	}
+
+	c, err := redis.Dial("", "", dialTestConn("-ERR invalid expire time in 'set' command\r\n", io.Discard))
+	require.NoError(t, err)
+	require.Error(t, redis.SetEx(c, "k", []byte("v"), time.Microsecond))
+	require.Error(t, redis.SetEx(c, "k", []byte("v"), time.Second))
+}
+
+func TestGetSet(t *testing.T) {
+	var buf bytes.Buffer
+	c, err := redis.Dial("", "", dialTestConn("$3\r\nold\r\n$-1\r\n", &buf))
+	require.NoError(t, err)
+	v, err := redis.GetSet(c, "k", []byte("new"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("old"), v)
+	require.Equal(t, "*3\r\n$6\r\nGETSET\r\n$1\r\nk\r\n$3\r\nnew\r\n", buf.String())
+
+	_, err = redis.GetSet(c, "k", []byte("new"))
+	require.Equal(t, redis.ErrNil, err)
+}
+
+func TestDoChecked(t *testing.T) {
+	var buf bytes.Buffer
+	c, err := redis.Dial("", "", dialTestConn("+list\r\n+hash\r\n:1\r\n+none\r\n:1\r\n", &buf))
+	require.NoError(t, err)
+
+	_, err = redis.DoChecked(c, redis.RedisTypeHash, "HSET", "k", "f", "v")
+	var e *redis.TypeMismatchError
+	require.True(t, errors.As(err, &e))
+	require.Equal(t, &redis.TypeMismatchError{Command: "HSET", Key: "k", Expected: redis.RedisTypeHash, Actual: redis.RedisTypeList}, e)
+	require.EqualError(t, err, `redigo: HSET on key "k" requires type hash, key holds type list`)
+	require.Equal(t, "*2\r\n$4\r\nTYPE\r\n$1\r\nk\r\n", buf.String(), "command sent after type mismatch")
+
+	buf.Reset()
+	n, err := redis.Int(redis.DoChecked(c, redis.RedisTypeHash, "HSET", "k", "f", "v"))
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	require.Equal(t, "*2\r\n$4\r\nTYPE\r\n$1\r\nk\r\n*4\r\n$4\r\nHSET\r\n$1\r\nk\r\n$1\r\nf\r\n$1\r\nv\r\n", buf.String())
+
+	n, err = redis.Int(redis.DoChecked(c, redis.RedisTypeHash, "HSET", []byte("new"), "f", "v"))
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+
+	_, err = redis.DoChecked(c, redis.RedisTypeHash, "HLEN")
+	require.Error(t, err)
+}
+
+func TestLRangeEach(t *testing.T) {
+	var buf bytes.Buffer
+	c, err := redis.Dial("", "", dialTestConn(
+		"*3\r\n$1\r\na\r\n$2\r\nbc\r\n$-1\r\n"+
+			"*3\r\n$1\r\na\r\n$1\r\nb\r\n$1\r\nc\r\n+OK\r\n"+
+			"-WRONGTYPE Operation against a key holding the wrong kind of value\r\n"+
+			"+OK\r\n*1\r\n$1\r\nz\r\n", &buf))
+	require.NoError(t, err)
+
+	var got []string
+	err = redis.LRangeEach(c, "k", 0, -1, func(i int, v []byte) error {
+		require.Equal(t, len(got), i)
+		if v == nil {
+			got = append(got, "<nil>")
+		} else {
+			got = append(got, string(v))
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"a", "bc", "<nil>"}, got)
+	require.Equal(t, "*4\r\n$6\r\nLRANGE\r\n$1\r\nk\r\n$1\r\n0\r\n$2\r\n-1\r\n", buf.String())
+
+	// An error from fn stops the callbacks and leaves the connection in sync.
+	errStop := errors.New("stop")
+	n := 0
+	err = redis.LRangeEach(c, "k", 0, -1, func(i int, v []byte) error {
+		n++
+		return errStop
+	})
+	require.Equal(t, errStop, err)
+	require.Equal(t, 1, n)
+	s, err := redis.String(c.Do("SET", "k", "v"))
+	require.NoError(t, err)
+	require.Equal(t, "OK", s)
+
+	err = redis.LRangeEach(c, "k", 0, -1, func(int, []byte) error { return nil })
+	require.EqualError(t, err, "WRONGTYPE Operation against a key holding the wrong kind of value")
+
+	// Replies to commands sent earlier are discarded.
+	require.NoError(t, c.Send("SET", "k", "v"))
+	got = got[:0]
+	err = redis.LRangeEach(c, "k", 0, -1, func(i int, v []byte) error {
+		got = append(got, string(v))
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"z"}, got)
+	require.NoError(t, c.Err())
+}
+
+func TestLRangeEachAllocs(t *testing.T) {
+	const (
+		runs     = 10
+		elements = 10000
+	)
+	var reply strings.Builder
+	for i := 0; i <= runs; i++ {
+		reply.WriteString("*" + strconv.Itoa(elements) + "\r\n")
+		for j := 0; j < elements; j++ {
+			reply.WriteString("$5\r\nvalue\r\n")
+		}
+	}
+	c, err := redis.Dial("", "", dialTestConn(reply.String(), io.Discard))
+	require.NoError(t, err)
+
+	var total int
+	allocs := testing.AllocsPerRun(runs, func() {
+		err := redis.LRangeEach(c, "k", 0, -1, func(i int, v []byte) error {
+			total += len(v)
+			return nil
+		})
+		require.NoError(t, err)
+	})
+	require.Equal(t, 5*elements*(runs+1), total)
+	require.Less(t, allocs, float64(10), "allocations do not depend on the number of elements")
+}
+
+func TestWaitDurable(t *testing.T) {
+	tests := []struct {
+		name        string
+		resp        string
+		numReplicas int
+		numLocal    int
+		replicas    int
+		local       int
+		err         error
+	}{
+		{"all", "*2\r\n:1\r\n:2\r\n", 2, 1, 2, 1, nil},
+		{"partial replicas", "*2\r\n:1\r\n:1\r\n", 2, 1, 1, 1, redis.ErrDurabilityTimeout},
+		{"partial local", "*2\r\n:0\r\n:2\r\n", 2, 1, 2, 0, redis.ErrDurabilityTimeout},
+		{"aof disabled", "-ERR WAITAOF cannot be used when numlocal is set but appendonly is disabled.\r\n", 0, 1, 0, 0, redis.ErrAOFDisabled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			c, err := redis.Dial("", "", dialTestConn(tt.resp, &buf))
+			require.NoError(t, err)
+			replicas, local, err := redis.WaitDurable(c, tt.numReplicas, tt.numLocal, 250*time.Millisecond)
+			require.Equal(t, tt.err, err)
+			require.Equal(t, tt.replicas, replicas)
+			require.Equal(t, tt.local, local)
+			require.Equal(t, "*4\r\n$7\r\nWAITAOF\r\n$1\r\n"+strconv.Itoa(tt.numLocal)+"\r\n$1\r\n"+strconv.Itoa(tt.numReplicas)+"\r\n$3\r\n250\r\n", buf.String())
+		})
+	}
+}
+
+func TestCommand(t *testing.T) {
+	tests := []struct {
+		name  string
+		parts []interface{}
+		w     string
+	}{
+		{"single word", []interface{}{"PING"}, "*1\r\n$4\r\nPING\r\n"},
+		{"subcommand", []interface{}{"DEBUG SET-ACTIVE-EXPIRE", 0}, "*3\r\n$5\r\nDEBUG\r\n$17\r\nSET-ACTIVE-EXPIRE\r\n$1\r\n0\r\n"},
+		{"extra spaces", []interface{}{" CONFIG  SET ", "maxmemory-policy", "allkeys lru"}, "*4\r\n$6\r\nCONFIG\r\n$3\r\nSET\r\n$16\r\nmaxmemory-policy\r\n$11\r\nallkeys lru\r\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			c, err := redis.Dial("", "", dialTestConn("+OK\r\n", &buf))
+			require.NoError(t, err)
+			reply, err := redis.Command(c, tt.parts...)
+			require.NoError(t, redis.StatusReply(reply, err, "OK"))
+			require.Equal(t, tt.w, buf.String())
+		})
+	}
+
+	c, err := redis.Dial("", "", dialTestConn("", io.Discard))
+	require.NoError(t, err)
+	_, err = redis.Command(c)
+	require.Error(t, err)
+	_, err = redis.Command(c, " ")
+	require.Error(t, err)
+	_, err = redis.Command(c, []byte("PING"))
+	require.Error(t, err)
+}
+
+func TestClientSetName(t *testing.T) {
+	var buf bytes.Buffer
+	c, err := redis.Dial("", "", dialTestConn("+OK\r\n", &buf))
+	require.NoError(t, err)
+
+	require.NoError(t, redis.ClientSetName(c, "worker-1"))
+	require.Equal(t, "*3\r\n$6\r\nCLIENT\r\n$7\r\nSETNAME\r\n$8\r\nworker-1\r\n", buf.String())
+
+	buf.Reset()
+	for _, name := range []string{"has space", "new\nline", "tab\t", "caf\xc3\xa9"} {
+		require.Error(t, redis.ClientSetName(c, name), name)
+	}
+	require.Equal(t, "", buf.String(), "command sent for invalid name")
+}
+
+func TestClientGetName(t *testing.T) {
+	c, err := redis.Dial("", "", dialTestConn("$8\r\nworker-1\r\n$-1\r\n", io.Discard))
+	require.NoError(t, err)
+
+	name, err := redis.ClientGetName(c)
+	require.NoError(t, err)
+	require.Equal(t, "worker-1", name)
+
+	name, err = redis.ClientGetName(c)
+	require.NoError(t, err)
+	require.Equal(t, "", name)
+}
@@ -26,14 +26,49 @@ import (
 	"net/url"
 	"regexp"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 )
 
 var (
 	_ ConnWithTimeout = (*conn)(nil)
+	_ ConnWithKey     = (*conn)(nil)
 )
 
+// ErrMaxPendingReplies is returned by Send when the number of commands sent
+// without receiving the reply reaches the limit set with
+// DialMaxPendingReplies.
+var ErrMaxPendingReplies = errors.New("redigo: too many pending replies")
+
+// CommandError is returned by Do and related methods when the
+// DialWrapCommandErrors option is set. CommandError records the command
+// that failed. Use errors.Is and errors.As to inspect the underlying error.
+type CommandError struct {
+	// Command is the name of the command.
+	Command string
+
+	// Args are the command arguments with the arguments registered with
+	// RegisterRedaction replaced by a placeholder.
+	Args []interface{}
+
+	// Err is the error returned by the command, usually an Error from the
+	// server or a network error.
+	Err error
+}
+
+func (err *CommandError) Error() string {
+	return "redigo: " + err.Command + ": " + err.Err.Error()
+}
+
+func (err *CommandError) Unwrap() error { return err.Err }
+
+// ErrCommandForbidden is returned by connections dialed with the
+// DialCommandPolicy option for commands forbidden by the policy. The
+// returned error wraps ErrCommandForbidden. Use errors.Is to check for the
+// error.
+var ErrCommandForbidden = errors.New("redigo: command forbidden by policy")
+
 // conn is the low-level implementation of Conn
 type conn struct {
 	// Shared
@@ -41,10 +76,21 @@ type conn struct {
 	pending int
 	err     error
 	conn    net.Conn
+	written bool // set when the command in the last Do was flushed
+
+	// Context bound to the connection with DialBindContext. The done channel
+	// is closed when the connection is closed.
+	ctx  context.Context
+	done chan struct{}
 
 	// Read
 	readTimeout time.Duration
 	br          *bufio.Reader
+	replyPool   *sync.Pool
+
+	// Value codec and, for each command sent, whether the reply is decoded.
+	codec       ValueCodec
+	decodeQueue []bool
 
 	// Write
 	writeTimeout time.Duration
@@ -56,6 +102,55 @@ type conn struct {
 
 	// Scratch space for formatting integers and floats.
 	numScratch [40]byte
+
+	// Arguments to AUTH when reauthenticating on NOAUTH errors. Nil when
+	// the DialReauthOnNoAuth option is not set.
+	reauthArgs []interface{}
+
+	// Cached reply from CLIENT ID. Zero if the ID is not known.
+	clientID int64
+
+	// Function called for commands slower than slowLogThreshold. Nil when
+	// the DialSlowLog option is not set.
+	slowLogThreshold time.Duration
+	slowLogFunc      func(cmd string, dur time.Duration)
+
+	// Recorder for command durations. Nil when the DialMetricsRecorder
+	// option is not set.
+	metrics MetricsRecorder
+
+	// Commands allowed and denied by DialCommandPolicy. Nil when the
+	// option is not set.
+	policy *commandPolicy
+
+	// Maximum number of pending replies. Zero when there is no limit.
+	maxPending int
+
+	// Replies are returned as RawReply when set. The buffer holds the last
+	// raw reply.
+	rawReplies bool
+	rawBuf     []byte
+
+	// Command names are uppercased before writing when set.
+	uppercaseCommands bool
+
+	// The selected database or -1 when the database is not known.
+	db int
+
+	// Errors returned by Do are wrapped in CommandError when set.
+	wrapCommandErrors bool
+
+	// Array replies are allocated from arrayBuf when set. The buffer holds
+	// the arrays of the replies read since the last reset.
+	reuseArrays bool
+	arrayBuf    []interface{}
+
+	// Buffer for the array elements passed to the DoEach callback.
+	eachBuf []byte
+
+	// Deadline for the next command set by SetCommandDeadline. Zero when
+	// not set.
+	commandDeadline time.Time
 }
 
 // DialTimeout acts like Dial but takes timeouts for establishing the
@@ -80,6 +175,7 @@ type dialOptions struct {
 	tlsHandshakeTimeout time.Duration
 	dialer              *net.Dialer
 	dialContext         func(ctx context.Context, network, addr string) (net.Conn, error)
+	network             string
 	db                  int
 	username            string
 	password            string
@@ -87,6 +183,21 @@ type dialOptions struct {
 	useTLS              bool
 	skipVerify          bool
 	tlsConfig           *tls.Config
+	reauthOnNoAuth      bool
+	replyPool           *sync.Pool
+	bindContext         bool
+	codec               ValueCodec
+	slowLogThreshold    time.Duration
+	slowLogFunc         func(cmd string, dur time.Duration)
+	maxPending          int
+	preTLSAuth          bool
+	onConnect           func(Conn) error
+	rawReplies          bool
+	uppercaseCommands   bool
+	wrapCommandErrors   bool
+	reuseArrays         bool
+	metrics             MetricsRecorder
+	policy              *commandPolicy
 }
 
 // DialTLSHandshakeTimeout specifies the maximum amount of time waiting to
@@ -151,6 +262,17 @@ func DialContextFunc(f func(ctx context.Context, network, addr string) (net.Conn
 	}}
 }
 
+// DialNetwork specifies the network used to connect to the Redis server, for
+// example "tcp4" or "tcp6" to force the IP version in dual-stack
+// environments. The option overrides the network argument to Dial and the
+// "tcp" network used by DialURL. When DialNetDial or DialContextFunc is
+// specified, the network is passed to the custom dial function.
+func DialNetwork(network string) DialOption {
+	return DialOption{func(do *dialOptions) {
+		do.network = network
+	}}
+}
+
 // DialDatabase specifies the database to select when dialing a connection.
 func DialDatabase(db int) DialOption {
 	return DialOption{func(do *dialOptions) {
@@ -175,6 +297,192 @@ func DialUsername(username string) DialOption {
 	}}
 }
 
+// DialReauthOnNoAuth specifies whether the connection should authenticate
+// again and retry the command once when a command returns a NOAUTH error.
+// This handles servers that enable authentication after the connection is
+// established. The option has no effect when DialPassword is not set.
+//
+// Because the command is executed a second time, the option should only be
+// used by applications where commands are safe to retry. If authentication
+// fails, then the connection is closed and the error is returned.
+func DialReauthOnNoAuth(reauth bool) DialOption {
+	return DialOption{func(do *dialOptions) {
+		do.reauthOnNoAuth = reauth
+	}}
+}
+
+// DialReplyBufferPool specifies a pool of buffers for bulk string replies.
+// The pool's values must be of type []byte. When a pooled buffer has
+// sufficient capacity for a bulk string, the reply is read into that buffer
+// instead of a newly allocated slice.
+//
+// This option is intended for read-heavy applications where bulk string
+// allocations dominate. The application must call ReleaseReply to return
+// the buffers in a reply to the pool and must not use or retain the reply,
+// or any value obtained from it without copying, after calling ReleaseReply.
+func DialReplyBufferPool(pool *sync.Pool) DialOption {
+	return DialOption{func(do *dialOptions) {
+		do.replyPool = pool
+	}}
+}
+
+// ReleaseReply returns the bulk strings in reply to pool. The reply must not
+// be used after it is released. See DialReplyBufferPool for details.
+func ReleaseReply(pool *sync.Pool, reply interface{}) {
+	switch reply := reply.(type) {
+	case []byte:
+		pool.Put(reply[:0]) // nolint: staticcheck
+	case []interface{}:
+		for _, r := range reply {
+			ReleaseReply(pool, r)
+		}
+	}
+}
+
+// DialValueCodec specifies a codec for values stored in Redis. The codec
+// encodes the value arguments of commands and decodes the replies that
+// contain values. Command names, keys and other arguments are not modified.
+//
+// The codec is applied to a built-in set of string, hash and list commands
+// including SET, MSET, GET, MGET, HSET, HGET, LPUSH and LRANGE. Other
+// commands, including Lua scripts, are sent and received without change.
+// Applications should only use the option when all access to the values goes
+// through the codec.
+func DialValueCodec(codec ValueCodec) DialOption {
+	return DialOption{func(do *dialOptions) {
+		do.codec = codec
+	}}
+}
+
+// DialSlowLog specifies a function that is called with the command name and
+// duration of each Do, DoContext and DoWithTimeout call that takes longer
+// than threshold. The duration includes writing the command and reading the
+// reply. The function is not called for fast commands.
+func DialSlowLog(threshold time.Duration, fn func(cmd string, dur time.Duration)) DialOption {
+	return DialOption{func(do *dialOptions) {
+		do.slowLogThreshold = threshold
+		do.slowLogFunc = fn
+	}}
+}
+
+// DialMaxPendingReplies specifies the maximum number of commands sent with
+// Send without receiving the reply. When the limit is reached, Send returns
+// ErrMaxPendingReplies until replies are received with Receive or Do. Zero
+// means no limit.
+func DialMaxPendingReplies(n int) DialOption {
+	return DialOption{func(do *dialOptions) {
+		do.maxPending = n
+	}}
+}
+
+// DialPreTLSAuth specifies whether AUTH is sent on the plaintext connection
+// before the TLS handshake instead of on the TLS connection after the
+// handshake.
+//
+// This is not standard Redis behavior. Redis servers and most proxies
+// expect AUTH after the TLS handshake, which is the default. Use the option
+// only with proxies that require authentication in a plaintext prelude
+// before upgrading the connection to TLS. The password is sent unencrypted
+// when the option is set. The option has no effect when DialUseTLS or
+// DialPassword is not set.
+func DialPreTLSAuth(preTLS bool) DialOption {
+	return DialOption{func(do *dialOptions) {
+		do.preTLSAuth = preTLS
+	}}
+}
+
+// DialOnConnect specifies a function that is called with each new
+// connection after the AUTH, CLIENT SETNAME and SELECT commands specified by
+// other options. Use the function to run setup commands such as CLIENT
+// NO-EVICT ON or READONLY. If the function returns an error, then the
+// connection is closed and the dial returns the error.
+func DialOnConnect(fn func(Conn) error) DialOption {
+	return DialOption{func(do *dialOptions) {
+		do.onConnect = fn
+	}}
+}
+
+// DialRawReplies specifies whether replies are returned as RawReply values
+// instead of decoded values. Use the option in proxies and other
+// applications that forward replies without inspecting them. Error replies
+// are returned as RawReply values and are not returned as errors. The
+// replies to the commands issued while dialing are decoded as usual.
+func DialRawReplies(raw bool) DialOption {
+	return DialOption{func(do *dialOptions) {
+		do.rawReplies = raw
+	}}
+}
+
+// DialUppercaseCommands specifies whether command names are converted to
+// upper case before they are written to the server. Use the option for
+// consistent command names at proxies and in server side metrics. Command
+// arguments are not changed.
+func DialUppercaseCommands(uppercase bool) DialOption {
+	return DialOption{func(do *dialOptions) {
+		do.uppercaseCommands = uppercase
+	}}
+}
+
+// DialWrapCommandErrors specifies whether the errors returned by Do,
+// DoContext and DoWithTimeout are wrapped in a CommandError that records the
+// command. The errors from the commands issued while dialing and from Send,
+// Flush and Receive are not wrapped.
+func DialWrapCommandErrors(wrap bool) DialOption {
+	return DialOption{func(do *dialOptions) {
+		do.wrapCommandErrors = wrap
+	}}
+}
+
+// DialReuseArrayBuffers specifies whether array replies are read into a
+// buffer that the connection reuses. The option reduces allocations for
+// applications that read many array replies and process each reply before
+// reading the next.
+//
+// The option is unsafe when replies are retained. An array reply returned
+// by Receive or Do, and every array nested in the reply, is valid only
+// until the next call to Receive or Do on the connection. Copy the arrays
+// to retain them. The values in the arrays, such as []byte bulk strings,
+// are not reused.
+func DialReuseArrayBuffers(reuse bool) DialOption {
+	return DialOption{func(do *dialOptions) {
+		do.reuseArrays = reuse
+	}}
+}
+
+// DialMetricsRecorder specifies a recorder for the duration of each command
+// issued with Do, DoContext and DoWithTimeout. The labels attached to the
+// context passed to DoContext with WithMetricLabels are passed to the
+// recorder. The commands issued while dialing are not recorded.
+func DialMetricsRecorder(r MetricsRecorder) DialOption {
+	return DialOption{func(do *dialOptions) {
+		do.metrics = r
+	}}
+}
+
+// DialCommandPolicy specifies the commands that the connection sends to the
+// server. Do and Send return an error wrapping ErrCommandForbidden without
+// sending the command when the command name is in deny, or when allow is not
+// empty and the command name is not in allow. The deny list takes precedence
+// over the allow list. Command names are compared case-insensitively with
+// the command verb, so a policy applies to all subcommands of a command such
+// as CONFIG. The commands issued while dialing and the commands sent with
+// DoRaw are not checked.
+func DialCommandPolicy(allow, deny []string) DialOption {
+	return DialOption{func(do *dialOptions) {
+		p := &commandPolicy{deny: make(map[string]bool, len(deny))}
+		if len(allow) > 0 {
+			p.allow = make(map[string]bool, len(allow))
+			for _, cmd := range allow {
+				p.allow[strings.ToUpper(cmd)] = true
+			}
+		}
+		for _, cmd := range deny {
+			p.deny[strings.ToUpper(cmd)] = true
+		}
+		do.policy = p
+	}}
+}
+
 // DialClientName specifies a client name to be used
 // by the Redis server connection.
 func DialClientName(name string) DialOption {
@@ -207,6 +515,20 @@ func DialUseTLS(useTLS bool) DialOption {
 	}}
 }
 
+// DialBindContext specifies whether the context passed to DialContext or
+// DialURLContext bounds the lifetime of the connection in addition to the
+// dial. When the option is set and the context is done, the connection is
+// closed and subsequent commands return the context's error.
+//
+// The option is intended for request-scoped connections. Do not use the
+// option with connections dialed by a Pool, because the context passed to
+// Pool.DialContext is scoped to a single call to Pool.GetContext.
+func DialBindContext(bind bool) DialOption {
+	return DialOption{func(do *dialOptions) {
+		do.bindContext = bind
+	}}
+}
+
 // Dial connects to the Redis server at the given network and
 // address using the specified options.
 func Dial(network, address string, options ...DialOption) (Conn, error) {
@@ -235,12 +557,38 @@ func DialContext(ctx context.Context, network, address string, options ...DialOp
 	if do.dialContext == nil {
 		do.dialContext = do.dialer.DialContext
 	}
+	if do.network != "" {
+		network = do.network
+	}
 
 	netConn, err := do.dialContext(ctx, network, address)
 	if err != nil {
 		return nil, err
 	}
 
+	var authArgs []interface{}
+	if do.password != "" {
+		authArgs = make([]interface{}, 0, 2)
+		if do.username != "" {
+			authArgs = append(authArgs, do.username)
+		}
+		authArgs = append(authArgs, do.password)
+	}
+
+	if do.useTLS && do.preTLSAuth && authArgs != nil {
+		pc := &conn{
+			conn:         netConn,
+			bw:           bufio.NewWriter(netConn),
+			br:           bufio.NewReader(netConn),
+			readTimeout:  do.readTimeout,
+			writeTimeout: do.writeTimeout,
+		}
+		if _, err := pc.Do("AUTH", authArgs...); err != nil {
+			netConn.Close()
+			return nil, err
+		}
+	}
+
 	if do.useTLS {
 		var tlsConfig *tls.Config
 		if do.tlsConfig == nil {
@@ -283,17 +631,19 @@ func DialContext(ctx context.Context, network, address string, options ...DialOp
 		br:           bufio.NewReader(netConn),
 		readTimeout:  do.readTimeout,
 		writeTimeout: do.writeTimeout,
+		replyPool:    do.replyPool,
+		db:           do.db,
 	}
 
-	if do.password != "" {
-		authArgs := make([]interface{}, 0, 2)
-		if do.username != "" {
-			authArgs = append(authArgs, do.username)
+	if authArgs != nil {
+		if !do.useTLS || !do.preTLSAuth {
+			if _, err := c.Do("AUTH", authArgs...); err != nil {
+				netConn.Close()
+				return nil, err
+			}
 		}
-		authArgs = append(authArgs, do.password)
-		if _, err := c.Do("AUTH", authArgs...); err != nil {
-			netConn.Close()
-			return nil, err
+		if do.reauthOnNoAuth {
+			c.reauthArgs = authArgs
 		}
 	}
 
@@ -311,6 +661,30 @@ func DialContext(ctx context.Context, network, address string, options ...DialOp
 		}
 	}
 
+	if do.onConnect != nil {
+		if err := do.onConnect(c); err != nil {
+			netConn.Close()
+			return nil, err
+		}
+	}
+
+	c.codec = do.codec
+	c.slowLogThreshold = do.slowLogThreshold
+	c.slowLogFunc = do.slowLogFunc
+	c.maxPending = do.maxPending
+	c.rawReplies = do.rawReplies
+	c.uppercaseCommands = do.uppercaseCommands
+	c.wrapCommandErrors = do.wrapCommandErrors
+	c.reuseArrays = do.reuseArrays
+	c.metrics = do.metrics
+	c.policy = do.policy
+
+	if do.bindContext && ctx.Done() != nil {
+		c.ctx = ctx
+		c.done = make(chan struct{})
+		go c.watchContext()
+	}
+
 	return c, nil
 }
 
@@ -399,6 +773,7 @@ func NewConn(netConn net.Conn, readTimeout, writeTimeout time.Duration) Conn {
 		br:           bufio.NewReader(netConn),
 		readTimeout:  readTimeout,
 		writeTimeout: writeTimeout,
+		db:           -1,
 	}
 }
 
@@ -408,6 +783,9 @@ func (c *conn) Close() error {
 	if c.err == nil {
 		c.err = errors.New("redigo: closed")
 		err = c.conn.Close()
+		if c.done != nil {
+			close(c.done)
+		}
 	}
 	c.mu.Unlock()
 	return err
@@ -420,11 +798,41 @@ func (c *conn) fatal(err error) error {
 		// Close connection to force errors on subsequent calls and to unblock
 		// other reader or writer.
 		c.conn.Close()
+		if c.done != nil {
+			close(c.done)
+		}
 	}
 	c.mu.Unlock()
 	return err
 }
 
+// abort fails the pending and subsequent operations on the connection with
+// err. Unlike Close, abort is safe to call concurrently with the methods of
+// the connection. The application still closes the connection as usual.
+func (c *conn) abort(err error) {
+	c.fatal(err) // nolint: errcheck
+}
+
+// watchContext closes the connection when the bound context is done.
+func (c *conn) watchContext() {
+	select {
+	case <-c.ctx.Done():
+		c.fatal(c.ctx.Err()) // nolint: errcheck
+	case <-c.done:
+	}
+}
+
+// contextErr returns the error for a done bound context.
+func (c *conn) contextErr() error {
+	if c.ctx == nil {
+		return nil
+	}
+	if err := c.ctx.Err(); err != nil {
+		return c.fatal(err)
+	}
+	return nil
+}
+
 func (c *conn) Err() error {
 	c.mu.Lock()
 	err := c.err
@@ -432,6 +840,47 @@ func (c *conn) Err() error {
 	return err
 }
 
+// commandWritten reports whether the command in the most recent call to Do
+// was completely written to the network.
+func (c *conn) commandWritten() bool {
+	c.mu.Lock()
+	written := c.written
+	c.mu.Unlock()
+	return written
+}
+
+// SetCommandDeadline sets the deadline for writing and reading the reply of
+// the next command issued with Do or DoWithTimeout. The deadline is cleared
+// when that command completes. When the connection also has a timeout, the
+// earlier of the deadline and the timeout applies. A zero value for t clears
+// the deadline.
+func (c *conn) SetCommandDeadline(t time.Time) error {
+	c.mu.Lock()
+	c.commandDeadline = t
+	c.mu.Unlock()
+	return nil
+}
+
+// ClientID returns the server assigned ID of the connection. The ID is
+// requested with the CLIENT ID command on the first call. Subsequent calls
+// return the cached value.
+func (c *conn) ClientID() (int64, error) {
+	c.mu.Lock()
+	id := c.clientID
+	c.mu.Unlock()
+	if id != 0 {
+		return id, nil
+	}
+	id, err := Int64(c.Do("CLIENT", "ID"))
+	if err != nil {
+		return 0, err
+	}
+	c.mu.Lock()
+	c.clientID = id
+	c.mu.Unlock()
+	return id, nil
+}
+
 func (c *conn) writeLen(prefix byte, n int) error {
 	c.lenScratch[len(c.lenScratch)-1] = '\n'
 	c.lenScratch[len(c.lenScratch)-2] = '\r'
@@ -480,9 +929,18 @@ func (c *conn) writeFloat64(n float64) error {
 }
 
 func (c *conn) writeCommand(cmd string, args []interface{}) error {
+	if c.codec != nil {
+		ci, _ := lookupCodecInfo(cmd)
+		c.mu.Lock()
+		c.decodeQueue = append(c.decodeQueue, ci.decode)
+		c.mu.Unlock()
+	}
 	if err := c.writeLen('*', 1+len(args)); err != nil {
 		return err
 	}
+	if c.uppercaseCommands {
+		cmd = strings.ToUpper(cmd)
+	}
 	if err := c.writeString(cmd); err != nil {
 		return err
 	}
@@ -622,6 +1080,113 @@ var (
 	pongReply interface{} = "PONG"
 )
 
+// bulkBuffer returns a slice of length n for reading a bulk string.
+func (c *conn) bulkBuffer(n int) []byte {
+	if c.replyPool != nil {
+		if p, ok := c.replyPool.Get().([]byte); ok && cap(p) >= n {
+			return p[:n]
+		}
+	}
+	return make([]byte, n)
+}
+
+// RawReply is an undecoded reply returned by connections dialed with the
+// DialRawReplies option. A RawReply returned by Receive or Do is valid only
+// until the next call to Receive or Do on the connection. Copy the bytes to
+// retain the reply.
+type RawReply struct {
+	p []byte
+}
+
+// Bytes returns the reply encoded in RESP.
+func (r RawReply) Bytes() []byte {
+	return r.p
+}
+
+// Decode decodes the reply. Decode returns an error reply as a value of type
+// Error, not as the error.
+func (r RawReply) Decode() (interface{}, error) {
+	c := &conn{br: bufio.NewReader(bytes.NewReader(r.p))}
+	return c.readReply()
+}
+
+// readRawReply reads the next reply without decoding it. The returned reply
+// uses the connection's raw reply buffer.
+func (c *conn) readRawReply() (RawReply, error) {
+	p, err := c.appendRawReply(c.rawBuf[:0])
+	c.rawBuf = p
+	if err != nil {
+		return RawReply{}, err
+	}
+	return RawReply{p}, nil
+}
+
+func (c *conn) appendRawReply(p []byte) ([]byte, error) {
+	line, err := c.readLine()
+	if err != nil {
+		return p, err
+	}
+	if len(line) == 0 {
+		return p, protocolError("short response line")
+	}
+	p = append(p, line...)
+	p = append(p, '\r', '\n')
+	switch line[0] {
+	case '+', '-', ':':
+		return p, nil
+	case '$':
+		n, err := parseLen(line[1:])
+		if n < 0 || err != nil {
+			return p, err
+		}
+		start := len(p)
+		if cap(p)-start < n+2 {
+			np := make([]byte, start, 2*cap(p)+n+2)
+			copy(np, p)
+			p = np
+		}
+		p = p[:start+n+2]
+		if _, err := io.ReadFull(c.br, p[start:]); err != nil {
+			return p, err
+		}
+		if p[len(p)-2] != '\r' || p[len(p)-1] != '\n' {
+			return p, protocolError("bad bulk string format")
+		}
+		return p, nil
+	case '*':
+		n, err := parseLen(line[1:])
+		if n < 0 || err != nil {
+			return p, err
+		}
+		for i := 0; i < n; i++ {
+			if p, err = c.appendRawReply(p); err != nil {
+				return p, err
+			}
+		}
+		return p, nil
+	}
+	return p, protocolError("unexpected response line")
+}
+
+// arrayBuffer returns a slice of length n for reading an array reply.
+func (c *conn) arrayBuffer(n int) []interface{} {
+	if !c.reuseArrays {
+		return make([]interface{}, n)
+	}
+	// Slices returned earlier remain valid when append reallocates the
+	// buffer because they keep the previous backing array.
+	i := len(c.arrayBuf)
+	c.arrayBuf = append(c.arrayBuf, make([]interface{}, n)...)
+	return c.arrayBuf[i : i+n : i+n]
+}
+
+// resetArrays makes the array buffer available for the next replies.
+func (c *conn) resetArrays() {
+	if c.reuseArrays {
+		c.arrayBuf = c.arrayBuf[:0]
+	}
+}
+
 func (c *conn) readReply() (interface{}, error) {
 	line, err := c.readLine()
 	if err != nil {
@@ -651,7 +1216,7 @@ func (c *conn) readReply() (interface{}, error) {
 		if n < 0 || err != nil {
 			return nil, err
 		}
-		p := make([]byte, n)
+		p := c.bulkBuffer(n)
 		_, err = io.ReadFull(c.br, p)
 		if err != nil {
 			return nil, err
@@ -667,7 +1232,7 @@ func (c *conn) readReply() (interface{}, error) {
 		if n < 0 || err != nil {
 			return nil, err
 		}
-		r := make([]interface{}, n)
+		r := c.arrayBuffer(n)
 		for i := range r {
 			r[i], err = c.readReply()
 			if err != nil {
@@ -680,8 +1245,28 @@ func (c *conn) readReply() (interface{}, error) {
 }
 
 func (c *conn) Send(cmd string, args ...interface{}) error {
+	if err := c.contextErr(); err != nil {
+		return err
+	}
+	if err := c.policy.check(cmd); err != nil {
+		return err
+	}
+	if c.codec != nil {
+		var err error
+		if args, err = encodeArgs(c.codec, cmd, args); err != nil {
+			return err
+		}
+	}
 	c.mu.Lock()
+	if c.maxPending > 0 && c.pending >= c.maxPending {
+		c.mu.Unlock()
+		return ErrMaxPendingReplies
+	}
 	c.pending += 1
+	if changesDB(cmd) {
+		// The reply is not seen by the connection.
+		c.db = -1
+	}
 	c.mu.Unlock()
 	if c.writeTimeout != 0 {
 		if err := c.conn.SetWriteDeadline(time.Now().Add(c.writeTimeout)); err != nil {
@@ -695,6 +1280,9 @@ func (c *conn) Send(cmd string, args ...interface{}) error {
 }
 
 func (c *conn) Flush() error {
+	if err := c.contextErr(); err != nil {
+		return err
+	}
 	if c.writeTimeout != 0 {
 		if err := c.conn.SetWriteDeadline(time.Now().Add(c.writeTimeout)); err != nil {
 			return c.fatal(err)
@@ -741,6 +1329,9 @@ func (c *conn) ReceiveContext(ctx context.Context) (interface{}, error) {
 }
 
 func (c *conn) ReceiveWithTimeout(timeout time.Duration) (reply interface{}, err error) {
+	if err := c.contextErr(); err != nil {
+		return nil, err
+	}
 	var deadline time.Time
 	if timeout != 0 {
 		deadline = time.Now().Add(timeout)
@@ -749,7 +1340,9 @@ func (c *conn) ReceiveWithTimeout(timeout time.Duration) (reply interface{}, err
 		return nil, c.fatal(err)
 	}
 
-	if reply, err = c.readReply(); err != nil {
+	var decodeErr error
+	c.resetArrays()
+	if reply, decodeErr, err = c.readCommandReply(); err != nil {
 		return nil, c.fatal(err)
 	}
 	// When using pub/sub, the number of receives can be greater than the
@@ -764,16 +1357,202 @@ func (c *conn) ReceiveWithTimeout(timeout time.Duration) (reply interface{}, err
 		c.pending -= 1
 	}
 	c.mu.Unlock()
+	if decodeErr != nil {
+		return nil, decodeErr
+	}
 	if err, ok := reply.(Error); ok {
 		return nil, err
 	}
 	return
 }
 
+// Drain reads and discards n replies. Drain is a last resort for recovering
+// a connection where the application lost track of the number of pending
+// replies. Closing the connection is usually the better choice.
+func (c *conn) Drain(n int) error {
+	if err := c.flushPending(); err != nil {
+		return err
+	}
+	for i := 0; i < n; i++ {
+		if _, err := c.Receive(); err != nil {
+			if _, ok := err.(Error); !ok {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// DrainAll reads and discards replies until no reply arrives within timeout
+// and returns the number of replies discarded. DrainAll is best effort: a
+// reply that arrives partially when the timeout elapses leaves the
+// connection out of sync. Closing the connection is usually the better
+// choice.
+func (c *conn) DrainAll(timeout time.Duration) (int, error) {
+	if err := c.contextErr(); err != nil {
+		return 0, err
+	}
+	if err := c.flushPending(); err != nil {
+		return 0, err
+	}
+	n := 0
+	for {
+		if err := c.conn.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+			return n, c.fatal(err)
+		}
+		c.resetArrays()
+		if _, err := c.readReply(); err != nil {
+			if e, ok := err.(net.Error); ok && e.Timeout() {
+				break
+			}
+			return n, c.fatal(err)
+		}
+		n++
+	}
+	c.mu.Lock()
+	c.pending = 0
+	c.decodeQueue = nil
+	c.mu.Unlock()
+	return n, nil
+}
+
+// flushPending flushes buffered commands so that their replies can be read.
+func (c *conn) flushPending() error {
+	if c.bw.Buffered() == 0 {
+		return nil
+	}
+	return c.Flush()
+}
+
+// DoEach sends a command to the server and calls fn with each element of
+// the array reply as the element is read from the connection. The value
+// passed to fn is valid only until fn returns. A nil bulk string element is
+// passed to fn as a nil value. If fn returns an error, then DoEach reads the
+// remaining elements without calling fn and returns the error. As with Do,
+// the replies to commands sent earlier with Send are read and discarded. The
+// reply is not processed by the connection's value codec.
+func (c *conn) DoEach(fn func(index int, value []byte) error, cmd string, args ...interface{}) error {
+	if err := c.Send(cmd, args...); err != nil {
+		return err
+	}
+	if err := c.Flush(); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	pending := c.pending
+	c.mu.Unlock()
+
+	var err error
+	for i := 1; i < pending; i++ {
+		if _, e := c.Receive(); e != nil {
+			if _, ok := e.(Error); !ok {
+				return e
+			}
+			if err == nil {
+				err = e
+			}
+		}
+	}
+
+	var deadline time.Time
+	if c.readTimeout != 0 {
+		deadline = time.Now().Add(c.readTimeout)
+	}
+	if e := c.conn.SetReadDeadline(deadline); e != nil {
+		return c.fatal(e)
+	}
+	replyErr, e := c.readEach(fn)
+	if e != nil {
+		return c.fatal(e)
+	}
+	c.mu.Lock()
+	if c.pending > 0 {
+		c.pending--
+	}
+	if len(c.decodeQueue) > 0 {
+		c.decodeQueue = c.decodeQueue[1:]
+	}
+	c.mu.Unlock()
+	if err == nil {
+		err = replyErr
+	}
+	return err
+}
+
+// readEach reads an array reply and calls fn with each element. Errors that
+// leave the connection in sync, including error replies and errors from fn,
+// are returned as replyErr.
+func (c *conn) readEach(fn func(index int, value []byte) error) (replyErr error, err error) {
+	if p, err := c.br.Peek(1); err != nil {
+		return nil, err
+	} else if p[0] != '*' {
+		reply, err := c.readReply()
+		if err != nil {
+			return nil, err
+		}
+		if e, ok := reply.(Error); ok {
+			return e, nil
+		}
+		return fmt.Errorf("redigo: unexpected type for DoEach, got type %T", reply), nil
+	}
+	line, err := c.readLine()
+	if err != nil {
+		return nil, err
+	}
+	n, err := parseLen(line[1:])
+	if n < 0 || err != nil {
+		return nil, err
+	}
+	for i := 0; i < n; i++ {
+		if p, err := c.br.Peek(1); err != nil {
+			return nil, err
+		} else if p[0] != '$' {
+			reply, err := c.readReply()
+			if err != nil {
+				return nil, err
+			}
+			if replyErr == nil {
+				replyErr = fmt.Errorf("redigo: unexpected element type for DoEach, got type %T", reply)
+			}
+			continue
+		}
+		line, err := c.readLine()
+		if err != nil {
+			return nil, err
+		}
+		m, err := parseLen(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		var value []byte
+		if m >= 0 {
+			if cap(c.eachBuf) < m+2 {
+				c.eachBuf = make([]byte, m+2)
+			}
+			value = c.eachBuf[:m+2]
+			if _, err := io.ReadFull(c.br, value); err != nil {
+				return nil, err
+			}
+			if value[m] != '\r' || value[m+1] != '\n' {
+				return nil, protocolError("bad bulk string format")
+			}
+			value = value[:m]
+		}
+		if replyErr == nil {
+			replyErr = fn(i, value)
+		}
+	}
+	return replyErr, nil
+}
+
 func (c *conn) Do(cmd string, args ...interface{}) (interface{}, error) {
 	return c.DoWithTimeout(c.readTimeout, cmd, args...)
 }
 
+func (c *conn) DoWithKey(routingKey string, cmd string, args ...interface{}) (interface{}, error) {
+	return c.Do(cmd, args...)
+}
+
 func (c *conn) DoContext(ctx context.Context, cmd string, args ...interface{}) (interface{}, error) {
 	var realTimeout time.Duration
 	if dl, ok := ctx.Deadline(); ok {
@@ -791,10 +1570,14 @@ func (c *conn) DoContext(ctx context.Context, cmd string, args ...interface{}) (
 	endch := make(chan struct{})
 	var r interface{}
 	var e error
+	var labels map[string]string
+	if c.metrics != nil {
+		labels = MetricLabels(ctx)
+	}
 	go func() {
 		defer close(endch)
 
-		r, e = c.DoWithTimeout(realTimeout, cmd, args...)
+		r, e = c.doWithLabels(realTimeout, labels, cmd, args...)
 	}()
 	select {
 	case <-ctx.Done():
@@ -804,20 +1587,133 @@ func (c *conn) DoContext(ctx context.Context, cmd string, args ...interface{}) (
 	}
 }
 
+// DoRaw writes the pre-encoded command resp to the connection, flushes the
+// connection and returns the reply. The caller is responsible for resp being
+// valid RESP that produces exactly one reply. The command is not processed by
+// the connection's value codec.
+func (c *conn) DoRaw(resp []byte) (interface{}, error) {
+	if err := c.contextErr(); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.pending += 1
+	if c.codec != nil {
+		c.decodeQueue = append(c.decodeQueue, false)
+	}
+	// The raw command can change the selected database.
+	c.db = -1
+	c.mu.Unlock()
+	if c.writeTimeout != 0 {
+		if err := c.conn.SetWriteDeadline(time.Now().Add(c.writeTimeout)); err != nil {
+			return nil, c.fatal(err)
+		}
+	}
+	if _, err := c.bw.Write(resp); err != nil {
+		return nil, c.fatal(err)
+	}
+	replies, err := Values(c.doWithTimeout(c.readTimeout, ""))
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range replies {
+		if e, ok := r.(Error); ok {
+			err = e
+			break
+		}
+	}
+	return replies[len(replies)-1], err
+}
+
 func (c *conn) DoWithTimeout(readTimeout time.Duration, cmd string, args ...interface{}) (interface{}, error) {
+	return c.doWithLabels(readTimeout, nil, cmd, args...)
+}
+
+// doWithLabels issues the command and reports the duration of the command
+// with labels to the metrics recorder.
+func (c *conn) doWithLabels(readTimeout time.Duration, labels map[string]string, cmd string, args ...interface{}) (interface{}, error) {
+	if err := c.policy.check(cmd); err != nil {
+		return nil, err
+	}
+	if (c.slowLogFunc == nil && c.metrics == nil) || cmd == "" {
+		reply, err := c.doWithReauth(readTimeout, cmd, args...)
+		return reply, c.wrapCommandError(cmd, args, err)
+	}
+	start := time.Now()
+	reply, err := c.doWithReauth(readTimeout, cmd, args...)
+	dur := time.Since(start)
+	if c.slowLogFunc != nil && dur > c.slowLogThreshold {
+		c.slowLogFunc(cmd, dur)
+	}
+	if c.metrics != nil {
+		c.metrics.RecordCommand(cmd, labels, dur, err)
+	}
+	return reply, c.wrapCommandError(cmd, args, err)
+}
+
+// wrapCommandError wraps err in a CommandError when the DialWrapCommandErrors
+// option is set.
+func (c *conn) wrapCommandError(cmd string, args []interface{}, err error) error {
+	if err == nil || !c.wrapCommandErrors || cmd == "" {
+		return err
+	}
+	return &CommandError{Command: cmd, Args: redactArgs(cmd, args), Err: err}
+}
+
+func (c *conn) doWithReauth(readTimeout time.Duration, cmd string, args ...interface{}) (interface{}, error) {
+	if err := c.contextErr(); err != nil {
+		return nil, err
+	}
+	if c.codec != nil && cmd != "" {
+		var err error
+		if args, err = encodeArgs(c.codec, cmd, args); err != nil {
+			return nil, err
+		}
+	}
+	if c.reauthArgs == nil || cmd == "" {
+		return c.doWithTimeout(readTimeout, cmd, args...)
+	}
+
+	c.mu.Lock()
+	pending := c.pending
+	c.mu.Unlock()
+
+	reply, err := c.doWithTimeout(readTimeout, cmd, args...)
+	if e, ok := err.(Error); !ok || pending != 0 || !strings.HasPrefix(string(e), "NOAUTH ") {
+		return reply, err
+	}
+	if _, err := c.doWithTimeout(readTimeout, "AUTH", c.reauthArgs...); err != nil {
+		return nil, c.fatal(err)
+	}
+	return c.doWithTimeout(readTimeout, cmd, args...)
+}
+
+func (c *conn) doWithTimeout(readTimeout time.Duration, cmd string, args ...interface{}) (interface{}, error) {
 	c.mu.Lock()
 	pending := c.pending
 	c.pending = 0
+	c.written = false
+	commandDeadline := c.commandDeadline
+	c.commandDeadline = time.Time{}
 	c.mu.Unlock()
 
 	if cmd == "" && pending == 0 {
 		return nil, nil
 	}
 
-	if c.writeTimeout != 0 {
-		if err := c.conn.SetWriteDeadline(time.Now().Add(c.writeTimeout)); err != nil {
+	if c.writeTimeout != 0 || !commandDeadline.IsZero() {
+		var deadline time.Time
+		if c.writeTimeout != 0 {
+			deadline = time.Now().Add(c.writeTimeout)
+		}
+		deadline = earlierDeadline(deadline, commandDeadline)
+		if err := c.conn.SetWriteDeadline(deadline); err != nil {
 			return nil, c.fatal(err)
 		}
+		if c.writeTimeout == 0 {
+			// Clear the command deadline. Other writes do not set a
+			// write deadline when the connection has no write timeout.
+			defer c.conn.SetWriteDeadline(time.Time{})
+		}
 	}
 
 	if cmd != "" {
@@ -830,21 +1726,35 @@ func (c *conn) DoWithTimeout(readTimeout time.Duration, cmd string, args ...inte
 		return nil, c.fatal(err)
 	}
 
+	c.mu.Lock()
+	c.written = true
+	c.mu.Unlock()
+
 	var deadline time.Time
 	if readTimeout != 0 {
 		deadline = time.Now().Add(readTimeout)
 	}
+	deadline = earlierDeadline(deadline, commandDeadline)
 	if err := c.conn.SetReadDeadline(deadline); err != nil {
 		return nil, c.fatal(err)
 	}
 
+	// The replies to a flush share the array buffer until the next call.
+	c.resetArrays()
 	if cmd == "" {
 		reply := make([]interface{}, pending)
 		for i := range reply {
-			r, e := c.readReply()
+			r, de, e := c.readCommandReply()
 			if e != nil {
 				return nil, c.fatal(e)
 			}
+			if de != nil {
+				r = de
+			}
+			if rr, ok := r.(RawReply); ok {
+				// Copy because the next read reuses the raw reply buffer.
+				r = RawReply{append([]byte(nil), rr.p...)}
+			}
 			reply[i] = r
 		}
 		return reply, nil
@@ -853,13 +1763,111 @@ func (c *conn) DoWithTimeout(readTimeout time.Duration, cmd string, args ...inte
 	var err error
 	var reply interface{}
 	for i := 0; i <= pending; i++ {
-		var e error
-		if reply, e = c.readReply(); e != nil {
+		var de, e error
+		if reply, de, e = c.readCommandReply(); e != nil {
 			return nil, c.fatal(e)
 		}
+		if de != nil && err == nil {
+			err = de
+		}
 		if e, ok := reply.(Error); ok && err == nil {
 			err = e
 		}
 	}
+	if changesDB(cmd) {
+		c.trackDB(cmd, args, reply, err)
+	}
 	return reply, err
 }
+
+// commandPolicy is the set of commands allowed and denied by the
+// DialCommandPolicy option. The keys are uppercase command names. A nil allow
+// map allows all commands that are not denied.
+type commandPolicy struct {
+	allow map[string]bool
+	deny  map[string]bool
+}
+
+// check returns an error if the policy forbids cmd. A nil policy allows all
+// commands.
+func (p *commandPolicy) check(cmd string) error {
+	if p == nil || cmd == "" {
+		return nil
+	}
+	name := strings.ToUpper(cmd)
+	if p.deny[name] || (p.allow != nil && !p.allow[name]) {
+		return fmt.Errorf("%w: %s", ErrCommandForbidden, name)
+	}
+	return nil
+}
+
+// changesDB returns whether cmd changes the selected database.
+func changesDB(cmd string) bool {
+	return strings.EqualFold(cmd, "SELECT") || strings.EqualFold(cmd, "RESET")
+}
+
+// trackDB records the database selected by the SELECT or RESET command.
+func (c *conn) trackDB(cmd string, args []interface{}, reply interface{}, err error) {
+	db := -1
+	if err == nil && strings.EqualFold(cmd, "RESET") {
+		db = 0
+	} else if reply == okReply && len(args) == 1 {
+		var s string
+		switch arg := args[0].(type) {
+		case []byte:
+			s = string(arg)
+		default:
+			s = fmt.Sprint(arg)
+		}
+		if n, err := strconv.Atoi(s); err == nil {
+			db = n
+		}
+	}
+	c.mu.Lock()
+	c.db = db
+	c.mu.Unlock()
+}
+
+// selectedDB returns the selected database. The boolean result is false when
+// the database is not known.
+func (c *conn) selectedDB() (int, bool) {
+	c.mu.Lock()
+	db := c.db
+	c.mu.Unlock()
+	return db, db >= 0
+}
+
+// earlierDeadline returns the earlier of the deadlines a and b where the zero
+// value means no deadline.
+func earlierDeadline(a, b time.Time) time.Time {
+	if a.IsZero() || (!b.IsZero() && b.Before(a)) {
+		return b
+	}
+	return a
+}
+
+// readCommandReply reads the reply to a command and decodes the reply when
+// the connection has a value codec. Errors from the codec are returned as
+// decodeErr and do not affect the state of the connection.
+func (c *conn) readCommandReply() (reply interface{}, decodeErr error, err error) {
+	if c.rawReplies {
+		reply, err = c.readRawReply()
+	} else {
+		reply, err = c.readReply()
+	}
+	if err != nil || c.codec == nil {
+		return reply, nil, err
+	}
+	var decode bool
+	c.mu.Lock()
+	if len(c.decodeQueue) > 0 {
+		decode = c.decodeQueue[0]
+		c.decodeQueue = c.decodeQueue[1:]
+	}
+	c.mu.Unlock()
+	if !decode {
+		return reply, nil, nil
+	}
+	reply, decodeErr = decodeReply(c.codec, reply)
+	return reply, decodeErr, nil
+}
@@ -19,6 +19,7 @@ import (
 	"context"
 	"crypto/tls"
 	"crypto/x509"
+	"errors"
 	"fmt"
 	"io"
 	"math"
@@ -26,6 +27,7 @@ import (
 	"os"
 	"reflect"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -142,6 +144,14 @@ var writeTests = []struct {
 		[]interface{}{"ECHO", true, false},
 		"*3\r\n$4\r\nECHO\r\n$1\r\n1\r\n$1\r\n0\r\n",
 	},
+	{
+		[]interface{}{"SCAN", 0, "MATCH", []byte("\xff\x00prefix:*")},
+		"*4\r\n$4\r\nSCAN\r\n$1\r\n0\r\n$5\r\nMATCH\r\n$10\r\n\xff\x00prefix:*\r\n",
+	},
+	{
+		[]interface{}{"SCAN", 0, "MATCH", "\xfe*"},
+		"*4\r\n$4\r\nSCAN\r\n$1\r\n0\r\n$5\r\nMATCH\r\n$2\r\n\xfe*\r\n",
+	},
 }
 
 func TestWrite(t *testing.T) {
@@ -552,6 +562,75 @@ func TestDialContextFunc(t *testing.T) {
 	}
 }
 
+func TestDialNetwork(t *testing.T) {
+	var network string
+	f := func(ctx context.Context, n, addr string) (net.Conn, error) {
+		network = n
+		return &testConn{}, nil
+	}
+
+	_, err := redis.Dial("tcp", "", redis.DialContextFunc(f), redis.DialNetwork("tcp4"))
+	require.NoError(t, err)
+	require.Equal(t, "tcp4", network)
+
+	_, err = redis.DialURL("redis://localhost", redis.DialContextFunc(f), redis.DialNetwork("tcp6"))
+	require.NoError(t, err)
+	require.Equal(t, "tcp6", network)
+}
+
+func TestDialBindContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	c, err := redis.DialContext(ctx, "", "", redis.DialBindContext(true), dialTestConn("+PONG\r\n", io.Discard))
+	require.NoError(t, err)
+	defer c.Close()
+
+	_, err = c.Do("PING")
+	require.NoError(t, err)
+
+	cancel()
+	_, err = c.Do("PING")
+	require.ErrorIs(t, err, context.Canceled)
+	require.ErrorIs(t, c.Err(), context.Canceled)
+}
+
+type routingConn struct {
+	redis.Conn
+	key string
+}
+
+func (c *routingConn) DoWithKey(routingKey string, cmd string, args ...interface{}) (interface{}, error) {
+	c.key = routingKey
+	return c.Conn.Do(cmd, args...)
+}
+
+func TestDoWithKey(t *testing.T) {
+	var buf bytes.Buffer
+	c, err := redis.Dial("", "", dialTestConn("+OK\r\n+OK\r\n", &buf))
+	require.NoError(t, err)
+
+	_, err = redis.DoWithKey(c, "key", "SET", "key", "value")
+	require.NoError(t, err)
+	require.Equal(t, "*3\r\n$3\r\nSET\r\n$3\r\nkey\r\n$5\r\nvalue\r\n", buf.String())
+
+	rc := &routingConn{Conn: c}
+	_, err = redis.DoWithKey(redis.NewLoggingConnFilter(rc, nil, "", func(string) bool { return true }), "dest", "PING")
+	require.NoError(t, err)
+	require.Equal(t, "dest", rc.key)
+}
+
+func TestClientID(t *testing.T) {
+	var buf bytes.Buffer
+	c, err := redis.Dial("", "", dialTestConn(":42\r\n", &buf))
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		id, err := redis.ClientID(c)
+		require.NoError(t, err)
+		require.Equal(t, int64(42), id)
+	}
+	require.Equal(t, "*2\r\n$6\r\nCLIENT\r\n$2\r\nID\r\n", buf.String())
+}
+
 func TestDialContext_CanceledContext(t *testing.T) {
 	addr, err := redis.DefaultServerAddr()
 	if err != nil {
@@ -764,6 +843,39 @@ func TestDialTLSSKipVerify(t *testing.T) {
 	checkPingPong(t, &buf, c)
 }
 
+func TestDialPreTLSAuth(t *testing.T) {
+	const auth = "*2\r\n$4\r\nAUTH\r\n$4\r\npass\r\n"
+	var plain bytes.Buffer
+	c, err := redis.Dial("tcp", "example.com:6379",
+		redis.DialNetDial(func(network, addr string) (net.Conn, error) {
+			client, server := net.Pipe()
+			go func() {
+				// The proxy expects AUTH in plaintext before the handshake.
+				p := make([]byte, len(auth))
+				if _, err := io.ReadFull(server, p); err != nil {
+					return
+				}
+				plain.Write(p)
+				io.WriteString(server, "+OK\r\n") // nolint: errcheck
+				tlsServer := tls.Server(server, &serverTLSConfig)
+				go io.Copy(tlsServer, strings.NewReader(pingResponse)) // nolint: errcheck
+				io.Copy(io.Discard, tlsServer)                         // nolint: errcheck
+			}()
+			return client, nil
+		}),
+		redis.DialTLSConfig(&clientTLSConfig),
+		redis.DialUseTLS(true),
+		redis.DialPassword("pass"),
+		redis.DialPreTLSAuth(true))
+	require.NoError(t, err)
+	defer c.Close()
+
+	require.Equal(t, auth, plain.String())
+	v, err := redis.String(c.Do("PING"))
+	require.NoError(t, err)
+	require.Equal(t, "PONG", v)
+}
+
 func TestDialUseACL(t *testing.T) {
 	var buf bytes.Buffer
 	_, err := redis.Dial("tcp", "localhost:6379",
@@ -782,6 +894,33 @@ func TestDialUseACL(t *testing.T) {
 	}
 }
 
+func TestDialReauthOnNoAuth(t *testing.T) {
+	var buf bytes.Buffer
+	c, err := redis.Dial("", "",
+		redis.DialPassword("password"),
+		redis.DialReauthOnNoAuth(true),
+		dialTestConn("+OK\r\n-NOAUTH Authentication required.\r\n+OK\r\n$3\r\nbar\r\n", &buf))
+	require.NoError(t, err)
+
+	v, err := redis.String(c.Do("GET", "foo"))
+	require.NoError(t, err)
+	require.Equal(t, "bar", v)
+
+	auth := "*2\r\n$4\r\nAUTH\r\n$8\r\npassword\r\n"
+	get := "*2\r\n$3\r\nGET\r\n$3\r\nfoo\r\n"
+	require.Equal(t, auth+get+auth+get, buf.String())
+
+	c, err = redis.Dial("", "",
+		redis.DialPassword("password"),
+		redis.DialReauthOnNoAuth(true),
+		dialTestConn("+OK\r\n-NOAUTH Authentication required.\r\n-WRONGPASS invalid password\r\n", io.Discard))
+	require.NoError(t, err)
+
+	_, err = c.Do("GET", "foo")
+	require.EqualError(t, err, "WRONGPASS invalid password")
+	require.Error(t, c.Err())
+}
+
 // Connect to an Redis instance using the Redis ACL system
 func ExampleDial_acl() {
 	c, err := redis.Dial("tcp", "localhost:6379",
@@ -831,6 +970,20 @@ func TestDialClientName(t *testing.T) {
 	}
 }
 
+func TestDialDatabase(t *testing.T) {
+	var buf bytes.Buffer
+	_, err := redis.Dial("", "",
+		redis.DialPassword("password"),
+		redis.DialClientName("name"),
+		redis.DialDatabase(3),
+		dialTestConn("+OK\r\n+OK\r\n+OK\r\n", &buf))
+	require.NoError(t, err)
+	expected := "*2\r\n$4\r\nAUTH\r\n$8\r\npassword\r\n" +
+		"*3\r\n$6\r\nCLIENT\r\n$7\r\nSETNAME\r\n$4\r\nname\r\n" +
+		"*2\r\n$6\r\nSELECT\r\n$1\r\n3\r\n"
+	require.Equal(t, expected, buf.String())
+}
+
 // Connect to local instance of Redis running on the default port.
 func ExampleDial() {
 	c, err := redis.Dial("tcp", ":6379")
@@ -976,6 +1129,89 @@ func BenchmarkDoPing(b *testing.B) {
 	}
 }
 
+// repeatReader returns the bytes in s over and over again.
+type repeatReader struct {
+	s string
+	i int
+}
+
+func (r *repeatReader) Read(p []byte) (int, error) {
+	n := copy(p, r.s[r.i:])
+	r.i = (r.i + n) % len(r.s)
+	return n, nil
+}
+
+func benchmarkReceiveBulk(b *testing.B, pool *sync.Pool) {
+	r := &repeatReader{s: "$1024\r\n" + strings.Repeat("x", 1024) + "\r\n"}
+	options := []redis.DialOption{redis.DialNetDial(func(network, addr string) (net.Conn, error) {
+		return &testConn{Reader: r, Writer: io.Discard}, nil
+	})}
+	if pool != nil {
+		options = append(options, redis.DialReplyBufferPool(pool))
+	}
+	c, err := redis.Dial("", "", options...)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		v, err := c.Receive()
+		if err != nil {
+			b.Fatal(err)
+		}
+		if pool != nil {
+			redis.ReleaseReply(pool, v)
+		}
+	}
+}
+
+func benchmarkReceiveArray(b *testing.B, reuse bool) {
+	r := &repeatReader{s: "*3\r\n:1\r\n*2\r\n:2\r\n:3\r\n+OK\r\n"}
+	c, err := redis.Dial("", "", redis.DialNetDial(func(network, addr string) (net.Conn, error) {
+		return &testConn{Reader: r, Writer: io.Discard}, nil
+	}), redis.DialReuseArrayBuffers(reuse))
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := c.Receive(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkReceiveArray(b *testing.B) {
+	benchmarkReceiveArray(b, false)
+}
+
+func BenchmarkReceiveArrayReuse(b *testing.B) {
+	benchmarkReceiveArray(b, true)
+}
+
+func BenchmarkReceiveBulk(b *testing.B) {
+	benchmarkReceiveBulk(b, nil)
+}
+
+func BenchmarkReceiveBulkPool(b *testing.B) {
+	benchmarkReceiveBulk(b, &sync.Pool{New: func() interface{} { return make([]byte, 0, 1024) }})
+}
+
+func TestReplyBufferPool(t *testing.T) {
+	buf := make([]byte, 0, 16)
+	pool := &sync.Pool{}
+	pool.Put(buf)
+	c, err := redis.Dial("", "", redis.DialReplyBufferPool(pool),
+		dialTestConn("*2\r\n$3\r\nfoo\r\n$3\r\nbar\r\n", nil))
+	require.NoError(t, err)
+	v, err := redis.ByteSlices(c.Receive())
+	require.NoError(t, err)
+	require.Equal(t, [][]byte{[]byte("foo"), []byte("bar")}, v)
+	require.Equal(t, &buf[:1][0], &v[0][0], "first reply not read into pooled buffer")
+}
+
 var clientTLSConfig, serverTLSConfig tls.Config
 
 func init() {
@@ -1081,3 +1317,339 @@ func TestWithTimeout(t *testing.T) {
 		}
 	}
 }
+
+func TestDoRaw(t *testing.T) {
+	var buf bytes.Buffer
+	c, err := redis.Dial("", "", dialTestConn("+OK\r\n$1\r\nv\r\n-ERR x\r\n", &buf))
+	require.NoError(t, err)
+
+	require.NoError(t, c.Send("SET", "k", "v"))
+	reply, err := redis.DoRaw(c, []byte("*2\r\n$3\r\nGET\r\n$1\r\nk\r\n"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("v"), reply)
+	require.Equal(t, "*3\r\n$3\r\nSET\r\n$1\r\nk\r\n$1\r\nv\r\n*2\r\n$3\r\nGET\r\n$1\r\nk\r\n", buf.String())
+
+	_, err = redis.DoRaw(c, []byte("PING\r\n"))
+	require.EqualError(t, err, "ERR x")
+}
+
+// slowReader delays each read by delay.
+type slowReader struct {
+	r     io.Reader
+	delay time.Duration
+}
+
+func (r slowReader) Read(p []byte) (int, error) {
+	time.Sleep(r.delay)
+	return r.r.Read(p)
+}
+
+func TestDialSlowLog(t *testing.T) {
+	var slow []string
+	c, err := redis.Dial("", "",
+		redis.DialNetDial(func(network, addr string) (net.Conn, error) {
+			return &testConn{
+				Reader: slowReader{strings.NewReader("+OK\r\n"), 20 * time.Millisecond},
+				Writer: io.Discard,
+			}, nil
+		}),
+		redis.DialSlowLog(10*time.Millisecond, func(cmd string, dur time.Duration) {
+			require.GreaterOrEqual(t, int64(dur), int64(10*time.Millisecond))
+			slow = append(slow, cmd)
+		}))
+	require.NoError(t, err)
+
+	_, err = c.Do("SET", "k", "v")
+	require.NoError(t, err)
+	require.Equal(t, []string{"SET"}, slow)
+
+	c, err = redis.Dial("", "", dialTestConn("+OK\r\n", io.Discard),
+		redis.DialSlowLog(time.Hour, func(cmd string, dur time.Duration) {
+			t.Errorf("unexpected slow command %s", cmd)
+		}))
+	require.NoError(t, err)
+	_, err = c.Do("GET", "k")
+	require.NoError(t, err)
+}
+
+type metricsRecord struct {
+	cmd    string
+	labels map[string]string
+	err    error
+}
+
+type testRecorder struct{ records []metricsRecord }
+
+func (r *testRecorder) RecordCommand(cmd string, labels map[string]string, dur time.Duration, err error) {
+	r.records = append(r.records, metricsRecord{cmd, labels, err})
+}
+
+func TestDialMetricsRecorder(t *testing.T) {
+	var r testRecorder
+	c, err := redis.Dial("", "", dialTestConn("+OK\r\n$1\r\nv\r\n-ERR x\r\n", io.Discard),
+		redis.DialMetricsRecorder(&r))
+	require.NoError(t, err)
+	cc := c.(redis.ConnWithContext)
+
+	labels := map[string]string{"operation": "checkout", "tenant": "acme"}
+	ctx := redis.WithMetricLabels(context.Background(), labels)
+	require.Equal(t, labels, redis.MetricLabels(ctx))
+	require.Nil(t, redis.MetricLabels(context.Background()))
+
+	_, err = cc.DoContext(ctx, "SET", "k", "v")
+	require.NoError(t, err)
+	_, err = c.Do("GET", "k")
+	require.NoError(t, err)
+	_, err = cc.DoContext(ctx, "INCR", "k")
+	require.Error(t, err)
+	_, err = c.Do("")
+	require.NoError(t, err)
+
+	require.Equal(t, []metricsRecord{
+		{"SET", labels, nil},
+		{"GET", nil, nil},
+		{"INCR", labels, redis.Error("ERR x")},
+	}, r.records)
+}
+
+func TestDialCommandPolicy(t *testing.T) {
+	tests := []struct {
+		name      string
+		allow     []string
+		deny      []string
+		cmd       string
+		forbidden bool
+	}{
+		{"deny", nil, []string{"FLUSHALL", "config"}, "flushall", true},
+		{"deny subcommand", nil, []string{"CONFIG"}, "CONFIG", true},
+		{"not denied", nil, []string{"FLUSHALL"}, "GET", false},
+		{"allow", []string{"get", "SET"}, nil, "GET", false},
+		{"not allowed", []string{"GET", "SET"}, nil, "DEL", true},
+		{"deny precedence", []string{"GET", "DEBUG"}, []string{"debug"}, "DEBUG", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			c, err := redis.Dial("", "", dialTestConn("+OK\r\n+OK\r\n", &buf),
+				redis.DialCommandPolicy(tt.allow, tt.deny))
+			require.NoError(t, err)
+
+			_, err = c.Do(tt.cmd, "k")
+			sendErr := c.Send(tt.cmd, "k")
+			if tt.forbidden {
+				require.True(t, errors.Is(err, redis.ErrCommandForbidden), "Do returned %v", err)
+				require.True(t, errors.Is(sendErr, redis.ErrCommandForbidden), "Send returned %v", sendErr)
+				require.Equal(t, "", buf.String(), "forbidden command sent")
+				require.NoError(t, c.Err())
+			} else {
+				require.NoError(t, err)
+				require.NoError(t, sendErr)
+				require.NotEqual(t, "", buf.String())
+			}
+		})
+	}
+}
+
+func TestDrain(t *testing.T) {
+	c, err := redis.Dial("", "", dialTestConn("+OK\r\n-ERR x\r\n$1\r\nv\r\n$1\r\nw\r\n", io.Discard))
+	require.NoError(t, err)
+
+	// Send three commands and drain the replies after losing count.
+	for i := 0; i < 3; i++ {
+		require.NoError(t, c.Send("GET", "k"))
+	}
+	require.NoError(t, redis.Drain(c, 3))
+	v, err := redis.String(c.Do("GET", "k"))
+	require.NoError(t, err)
+	require.Equal(t, "w", v)
+}
+
+func TestDrainAll(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+	go func() {
+		io.WriteString(server, "+OK\r\n:1\r\n:2\r\n") // nolint: errcheck
+		buf := make([]byte, 64)
+		for {
+			if _, err := server.Read(buf); err != nil {
+				return
+			}
+			io.WriteString(server, "+PONG\r\n") // nolint: errcheck
+		}
+	}()
+	c := redis.NewConn(client, 0, 0)
+	defer c.Close()
+
+	n, err := redis.DrainAll(c, 50*time.Millisecond)
+	require.NoError(t, err)
+	require.Equal(t, 3, n)
+	require.NoError(t, c.Err())
+
+	v, err := redis.String(c.Do("PING"))
+	require.NoError(t, err)
+	require.Equal(t, "PONG", v)
+}
+
+func TestDialMaxPendingReplies(t *testing.T) {
+	c, err := redis.Dial("", "", dialTestConn("+OK\r\n+OK\r\n+OK\r\n+OK\r\n", io.Discard), redis.DialMaxPendingReplies(2))
+	require.NoError(t, err)
+
+	require.NoError(t, c.Send("PING"))
+	require.NoError(t, c.Send("PING"))
+	require.ErrorIs(t, c.Send("PING"), redis.ErrMaxPendingReplies)
+	require.NoError(t, c.Err())
+
+	require.NoError(t, c.Flush())
+	_, err = c.Receive()
+	require.NoError(t, err)
+	require.NoError(t, c.Send("PING"))
+	require.ErrorIs(t, c.Send("PING"), redis.ErrMaxPendingReplies)
+
+	// Do receives all pending replies.
+	_, err = c.Do("")
+	require.NoError(t, err)
+	require.NoError(t, c.Send("PING"))
+}
+
+func TestDialOnConnect(t *testing.T) {
+	var buf bytes.Buffer
+	calls := 0
+	c, err := redis.Dial("", "", dialTestConn("+OK\r\n+OK\r\n", &buf),
+		redis.DialDatabase(2),
+		redis.DialOnConnect(func(c redis.Conn) error {
+			calls++
+			_, err := c.Do("CLIENT", "NO-EVICT", "ON")
+			return err
+		}))
+	require.NoError(t, err)
+	require.Equal(t, 1, calls)
+	require.Equal(t, "*2\r\n$6\r\nSELECT\r\n$1\r\n2\r\n*3\r\n$6\r\nCLIENT\r\n$8\r\nNO-EVICT\r\n$2\r\nON\r\n", buf.String())
+	require.NoError(t, c.Close())
+
+	_, err = redis.Dial("", "", dialTestConn("-ERR unknown subcommand\r\n", io.Discard),
+		redis.DialOnConnect(func(c redis.Conn) error {
+			_, err := c.Do("CLIENT", "NO-EVICT", "ON")
+			return err
+		}))
+	require.EqualError(t, err, "ERR unknown subcommand")
+}
+
+func TestDialRawReplies(t *testing.T) {
+	const r = "+OK\r\n*3\r\n$3\r\nabc\r\n:1\r\n*1\r\n$-1\r\n-ERR x\r\n+A\r\n+B\r\n"
+	c, err := redis.Dial("", "", dialTestConn(r, io.Discard), redis.DialDatabase(1), redis.DialRawReplies(true))
+	require.NoError(t, err)
+
+	reply, err := c.Do("MGET", "a")
+	require.NoError(t, err)
+	raw, ok := reply.(redis.RawReply)
+	require.True(t, ok)
+	require.Equal(t, "*3\r\n$3\r\nabc\r\n:1\r\n*1\r\n$-1\r\n", string(raw.Bytes()))
+	v, err := raw.Decode()
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{[]byte("abc"), int64(1), []interface{}{nil}}, v)
+
+	reply, err = c.Do("GET", "a")
+	require.NoError(t, err)
+	require.Equal(t, "-ERR x\r\n", string(reply.(redis.RawReply).Bytes()))
+	v, err = reply.(redis.RawReply).Decode()
+	require.NoError(t, err)
+	require.Equal(t, redis.Error("ERR x"), v)
+
+	require.NoError(t, c.Send("PING"))
+	require.NoError(t, c.Send("PING"))
+	replies, err := redis.Values(c.Do(""))
+	require.NoError(t, err)
+	require.Equal(t, "+A\r\n", string(replies[0].(redis.RawReply).Bytes()))
+	require.Equal(t, "+B\r\n", string(replies[1].(redis.RawReply).Bytes()))
+}
+
+func TestSetCommandDeadline(t *testing.T) {
+	tc := &testConn{Reader: strings.NewReader("+OK\r\n+OK\r\n"), Writer: io.Discard}
+	c, err := redis.Dial("", "", redis.DialNetDial(func(network, addr string) (net.Conn, error) {
+		return tc, nil
+	}))
+	require.NoError(t, err)
+
+	deadline := time.Now().Add(time.Hour)
+	require.NoError(t, redis.SetCommandDeadline(c, deadline))
+	_, err = c.Do("PING")
+	require.NoError(t, err)
+	require.Equal(t, deadline, tc.readDeadline)
+	require.True(t, tc.writeDeadline.IsZero(), "write deadline not cleared")
+
+	_, err = c.Do("PING")
+	require.NoError(t, err)
+	require.True(t, tc.readDeadline.IsZero(), "deadline used by second command")
+}
+
+func TestDialUppercaseCommands(t *testing.T) {
+	var buf bytes.Buffer
+	c, err := redis.Dial("", "", dialTestConn("+OK\r\n+OK\r\n", &buf), redis.DialUppercaseCommands(true))
+	require.NoError(t, err)
+	_, err = c.Do("set", "key", "value")
+	require.NoError(t, err)
+	require.NoError(t, c.Send("Set", "key", []byte("value")))
+	_, err = c.Do("")
+	require.NoError(t, err)
+	require.Equal(t, "*3\r\n$3\r\nSET\r\n$3\r\nkey\r\n$5\r\nvalue\r\n*3\r\n$3\r\nSET\r\n$3\r\nkey\r\n$5\r\nvalue\r\n", buf.String())
+
+	buf.Reset()
+	c, err = redis.Dial("", "", dialTestConn("+OK\r\n", &buf))
+	require.NoError(t, err)
+	_, err = c.Do("set", "key", "value")
+	require.NoError(t, err)
+	require.Equal(t, "*3\r\n$3\r\nset\r\n$3\r\nkey\r\n$5\r\nvalue\r\n", buf.String())
+}
+
+func TestDialWrapCommandErrors(t *testing.T) {
+	c, err := redis.Dial("", "", dialTestConn("-ERR not an integer\r\n-WRONGPASS invalid\r\n$-1\r\n", io.Discard), redis.DialWrapCommandErrors(true))
+	require.NoError(t, err)
+
+	_, err = c.Do("INCR", "k")
+	var ce *redis.CommandError
+	require.True(t, errors.As(err, &ce))
+	require.Equal(t, "INCR", ce.Command)
+	require.Equal(t, []interface{}{"k"}, ce.Args)
+	var re redis.Error
+	require.True(t, errors.As(err, &re))
+	require.Equal(t, redis.Error("ERR not an integer"), re)
+	require.EqualError(t, err, "redigo: INCR: ERR not an integer")
+
+	_, err = c.Do("AUTH", "secret")
+	require.True(t, errors.As(err, &ce))
+	require.Equal(t, []interface{}{"<redacted>"}, ce.Args)
+
+	_, err = redis.String(c.Do("GET", "k"))
+	require.Equal(t, redis.ErrNil, err)
+
+	_, err = c.Do("GET", "k")
+	require.True(t, errors.As(err, &ce))
+	require.ErrorIs(t, err, io.EOF)
+}
+
+func TestDialReuseArrayBuffers(t *testing.T) {
+	c, err := redis.Dial("", "", dialTestConn("*2\r\n:0\r\n*1\r\n:0\r\n*2\r\n:1\r\n*1\r\n:2\r\n*2\r\n:3\r\n*1\r\n:4\r\n+OK\r\n+PONG\r\n", io.Discard), redis.DialReuseArrayBuffers(true))
+	require.NoError(t, err)
+
+	// Grow the buffer to the size of the replies.
+	_, err = c.Receive()
+	require.NoError(t, err)
+
+	first, err := redis.Values(c.Receive())
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{int64(1), []interface{}{int64(2)}}, first)
+	retained := first
+
+	// The next reply reuses the arrays of the previous reply.
+	second, err := redis.Values(c.Receive())
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{int64(3), []interface{}{int64(4)}}, second)
+	require.Equal(t, []interface{}{int64(3), []interface{}{int64(4)}}, retained)
+
+	// The replies to a flush do not share arrays.
+	require.NoError(t, c.Send("A"))
+	require.NoError(t, c.Send("B"))
+	replies, err := redis.Values(c.Do(""))
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{"OK", "PONG"}, replies)
+}
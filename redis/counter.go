@@ -0,0 +1,189 @@
+// Copyright 2012 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package redis
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+var errCounterClosed = errors.New("redigo: Counter closed")
+
+// CounterOptions specifies when a Counter flushes the buffered increments.
+type CounterOptions struct {
+	// FlushInterval is the time between flushes. If the value is zero, then
+	// the counter flushes only when MaxKeys is reached or Flush is called.
+	FlushInterval time.Duration
+
+	// MaxKeys is the number of buffered keys that triggers a flush. If the
+	// value is zero, then the number of keys is not limited.
+	MaxKeys int
+
+	// OnError is called with the errors from the flushes started by the
+	// counter. If OnError is nil, then the errors are ignored.
+	OnError func(err error)
+}
+
+// Counter coalesces increments to the same key and periodically sends the
+// sum of the increments for each key with INCRBY. The INCRBY commands for a
+// flush are pipelined on a single connection from the pool. Counter trades
+// consistency for throughput in workloads such as metrics: the values in
+// Redis lag behind the calls to Incr, and the buffered increments are lost
+// if the process exits before a flush or a flush fails. An increment is
+// applied at most once.
+//
+// A Counter is safe for concurrent use by multiple goroutines. Call Close to
+// flush the buffered increments and stop the counter.
+type Counter struct {
+	pool *Pool
+	opts CounterOptions
+
+	flush chan struct{}
+	done  chan struct{}
+	wg    sync.WaitGroup
+
+	flushMu sync.Mutex // flushMu serializes flushes
+
+	mu     sync.Mutex // mu protects the following fields
+	deltas map[string]int64
+	closed bool
+}
+
+// NewCounter returns a Counter that sends increments to Redis with
+// connections from pool.
+func NewCounter(pool *Pool, opts CounterOptions) *Counter {
+	c := &Counter{
+		pool:   pool,
+		opts:   opts,
+		flush:  make(chan struct{}, 1),
+		done:   make(chan struct{}),
+		deltas: make(map[string]int64),
+	}
+	c.wg.Add(1)
+	go c.loop()
+	return c
+}
+
+// Incr adds delta to the buffered increment for key.
+func (c *Counter) Incr(key string, delta int64) {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		c.reportError(errCounterClosed)
+		return
+	}
+	c.deltas[key] += delta
+	full := c.opts.MaxKeys > 0 && len(c.deltas) >= c.opts.MaxKeys
+	c.mu.Unlock()
+	if full {
+		select {
+		case c.flush <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Flush sends the buffered increments to Redis and returns the first error.
+// The increments are removed from the buffer before sending and are not
+// retried when the flush fails.
+func (c *Counter) Flush() error {
+	c.flushMu.Lock()
+	defer c.flushMu.Unlock()
+
+	c.mu.Lock()
+	deltas := c.deltas
+	c.deltas = make(map[string]int64, len(deltas))
+	c.mu.Unlock()
+	if len(deltas) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(deltas))
+	for k := range deltas {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var p Pipeline
+	for _, k := range keys {
+		if deltas[k] != 0 {
+			p.Add("INCRBY", k, deltas[k])
+		}
+	}
+	if p.Len() == 0 {
+		return nil
+	}
+
+	conn, err := c.pool.GetContext(context.Background())
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	replies, err := p.Exec(conn, PipelineOptions{})
+	if err != nil {
+		return err
+	}
+	for _, r := range replies {
+		if e, ok := r.(Error); ok {
+			return e
+		}
+	}
+	return nil
+}
+
+// Close flushes the buffered increments, stops the counter and returns the
+// error from the flush. Calls to Incr after Close report an error to
+// OnError.
+func (c *Counter) Close() error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil
+	}
+	c.closed = true
+	c.mu.Unlock()
+	close(c.done)
+	c.wg.Wait()
+	return c.Flush()
+}
+
+func (c *Counter) reportError(err error) {
+	if c.opts.OnError != nil {
+		c.opts.OnError(err)
+	}
+}
+
+func (c *Counter) loop() {
+	defer c.wg.Done()
+	var tick <-chan time.Time
+	if c.opts.FlushInterval > 0 {
+		t := time.NewTicker(c.opts.FlushInterval)
+		defer t.Stop()
+		tick = t.C
+	}
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-tick:
+		case <-c.flush:
+		}
+		if err := c.Flush(); err != nil {
+			c.reportError(err)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright 2012 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package redis_test
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/stretchr/testify/require"
+)
+
+// syncBuffer is a bytes.Buffer that is safe for concurrent use.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestCounter(t *testing.T) {
+	var buf syncBuffer
+	p := &redis.Pool{
+		MaxIdle: 1,
+		Dial: func() (redis.Conn, error) {
+			return redis.Dial("", "", dialTestConn(":3\r\n:5\r\n-ERR x\r\n", &buf))
+		},
+	}
+	defer p.Close()
+	c := redis.NewCounter(p, redis.CounterOptions{})
+
+	c.Incr("a", 1)
+	c.Incr("b", 5)
+	c.Incr("a", 2)
+	c.Incr("z", 1)
+	c.Incr("z", -1)
+	require.NoError(t, c.Flush())
+	require.Equal(t, "*3\r\n$6\r\nINCRBY\r\n$1\r\na\r\n$1\r\n3\r\n*3\r\n$6\r\nINCRBY\r\n$1\r\nb\r\n$1\r\n5\r\n", buf.String())
+
+	// Flushing an empty counter does not send commands.
+	require.NoError(t, c.Flush())
+	require.Equal(t, 2, bytes.Count([]byte(buf.String()), []byte("INCRBY")))
+
+	c.Incr("a", 1)
+	require.EqualError(t, c.Close(), "ERR x")
+
+	var errs []error
+	c = redis.NewCounter(p, redis.CounterOptions{OnError: func(err error) { errs = append(errs, err) }})
+	require.NoError(t, c.Close())
+	c.Incr("a", 1)
+	require.Len(t, errs, 1)
+}
+
+func TestCounterMaxKeys(t *testing.T) {
+	var buf syncBuffer
+	p := &redis.Pool{
+		MaxIdle: 1,
+		Dial: func() (redis.Conn, error) {
+			return redis.Dial("", "", dialTestConn(":1\r\n:1\r\n", &buf))
+		},
+	}
+	defer p.Close()
+	c := redis.NewCounter(p, redis.CounterOptions{MaxKeys: 2, FlushInterval: time.Hour})
+	defer c.Close()
+
+	c.Incr("a", 1)
+	c.Incr("b", 1)
+	require.Eventually(t, func() bool {
+		return buf.String() == "*3\r\n$6\r\nINCRBY\r\n$1\r\na\r\n$1\r\n1\r\n*3\r\n$6\r\nINCRBY\r\n$1\r\nb\r\n$1\r\n1\r\n"
+	}, time.Second, time.Millisecond)
+}
@@ -0,0 +1,82 @@
+// Copyright 2012 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package redis
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Pipe sends cmds to the server in a single pipeline, receives the replies
+// and converts each reply with convert. Reply helpers such as String and
+// Int64 can be used as convert. All replies are received before returning
+// so that the connection remains usable. If any command or conversion
+// fails, then Pipe returns the first error.
+//
+//	values, err := redis.Pipe(c, redis.String,
+//	    redis.PipeCommand{Name: "GET", Args: []interface{}{"a"}},
+//	    redis.PipeCommand{Name: "GET", Args: []interface{}{"b"}})
+func Pipe[T any](c Conn, convert func(interface{}, error) (T, error), cmds ...PipeCommand) ([]T, error) {
+	if err := sendCommands(c, cmds); err != nil {
+		return nil, err
+	}
+	if err := c.Flush(); err != nil {
+		return nil, err
+	}
+
+	result := make([]T, len(cmds))
+	var err error
+	for i := range cmds {
+		v, e := convert(c.Receive())
+		if e != nil {
+			if err == nil {
+				err = e
+			}
+			continue
+		}
+		result[i] = v
+	}
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Expect is a helper that asserts that a command reply has type T. Unlike
+// the converting helpers such as Int and String, Expect does not convert the
+// reply. Use Expect to get the reply as the protocol type: int64 for
+// integers, string for simple strings, []byte for bulk strings and
+// []interface{} for arrays. If err is not equal to nil, then Expect returns
+// err. A nil reply returns ErrNil and an error reply is returned as the
+// error.
+func Expect[T any](reply interface{}, err error) (T, error) {
+	var zero T
+	if err != nil {
+		return zero, err
+	}
+	if v, ok := reply.(T); ok {
+		return v, nil
+	}
+	switch reply := reply.(type) {
+	case nil:
+		return zero, ErrNil
+	case Error:
+		return zero, reply
+	}
+	return zero, fmt.Errorf("redigo: unexpected type for Expect, got type %T, want type %v", reply, reflect.TypeOf((*T)(nil)).Elem())
+}
@@ -0,0 +1,89 @@
+// Copyright 2012 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package redis_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPipe(t *testing.T) {
+	var buf bytes.Buffer
+	c, err := redis.Dial("", "", dialTestConn("$1\r\n1\r\n$1\r\n2\r\n", &buf))
+	require.NoError(t, err)
+
+	values, err := redis.Pipe(c, redis.String,
+		redis.PipeCommand{Name: "GET", Args: []interface{}{"a"}},
+		redis.PipeCommand{Name: "GET", Args: []interface{}{"b"}})
+	require.NoError(t, err)
+	require.Equal(t, []string{"1", "2"}, values)
+	require.Equal(t, "*2\r\n$3\r\nGET\r\n$1\r\na\r\n*2\r\n$3\r\nGET\r\n$1\r\nb\r\n", buf.String())
+
+	c, err = redis.Dial("", "", dialTestConn("$1\r\n1\r\n$-1\r\n", &buf))
+	require.NoError(t, err)
+	_, err = redis.Pipe(c, redis.String,
+		redis.PipeCommand{Name: "GET", Args: []interface{}{"a"}},
+		redis.PipeCommand{Name: "GET", Args: []interface{}{"b"}})
+	require.ErrorIs(t, err, redis.ErrNil)
+
+	// The reply to the command sent before a failed Send is drained.
+	c, err = redis.Dial("", "", dialTestConn("$1\r\n1\r\n+PONG\r\n", io.Discard), redis.DialMaxPendingReplies(1))
+	require.NoError(t, err)
+	_, err = redis.Pipe(c, redis.String,
+		redis.PipeCommand{Name: "GET", Args: []interface{}{"a"}},
+		redis.PipeCommand{Name: "GET", Args: []interface{}{"b"}})
+	require.Equal(t, redis.ErrMaxPendingReplies, err)
+	s, err := redis.String(c.Do("PING"))
+	require.NoError(t, err)
+	require.Equal(t, "PONG", s)
+}
+
+func TestExpect(t *testing.T) {
+	i, err := redis.Expect[int64](int64(42), nil)
+	require.NoError(t, err)
+	require.Equal(t, int64(42), i)
+
+	s, err := redis.Expect[string]("OK", nil)
+	require.NoError(t, err)
+	require.Equal(t, "OK", s)
+
+	b, err := redis.Expect[[]byte]([]byte("v"), nil)
+	require.NoError(t, err)
+	require.Equal(t, []byte("v"), b)
+
+	a, err := redis.Expect[[]interface{}]([]interface{}{int64(1), nil}, nil)
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{int64(1), nil}, a)
+
+	_, err = redis.Expect[int64]([]byte("42"), nil)
+	require.EqualError(t, err, "redigo: unexpected type for Expect, got type []uint8, want type int64")
+
+	_, err = redis.Expect[[]byte](nil, nil)
+	require.ErrorIs(t, err, redis.ErrNil)
+
+	_, err = redis.Expect[int64](redis.Error("ERR x"), nil)
+	require.EqualError(t, err, "ERR x")
+
+	e, err := redis.Expect[redis.Error](redis.Error("ERR x"), nil)
+	require.NoError(t, err)
+	require.Equal(t, redis.Error("ERR x"), e)
+}
@@ -19,11 +19,14 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"strings"
+	"sync"
 	"time"
 )
 
 var (
 	_ ConnWithTimeout = (*loggingConn)(nil)
+	_ ConnWithKey     = (*loggingConn)(nil)
 )
 
 // NewLoggingConn returns a logging wrapper around a connection.
@@ -42,6 +45,61 @@ func NewLoggingConnFilter(conn Conn, logger *log.Logger, prefix string, skip fun
 	return &loggingConn{conn, logger, prefix, skip}
 }
 
+const redactedArg = "<redacted>"
+
+var (
+	redactionsMu sync.RWMutex
+	redactions   = map[string][]int{"AUTH": {-1}}
+)
+
+// RegisterRedaction specifies that the argument at argIndex of the command
+// is replaced with "<redacted>" by logging connections. The argument index
+// does not include the command name. A negative index counts from the end
+// of the arguments. The password arguments of the AUTH and HELLO commands
+// are redacted by default.
+func RegisterRedaction(commandName string, argIndex int) {
+	commandName = strings.ToUpper(commandName)
+	redactionsMu.Lock()
+	redactions[commandName] = append(redactions[commandName], argIndex)
+	redactionsMu.Unlock()
+}
+
+// redactArgs returns args with the redacted arguments replaced. The args
+// slice is copied before modification.
+func redactArgs(commandName string, args []interface{}) []interface{} {
+	commandName = strings.ToUpper(commandName)
+	var redact []int
+	if commandName == "HELLO" {
+		// HELLO [protover [AUTH username password] [SETNAME clientname]]
+		for i, arg := range args {
+			if s, ok := arg.(string); ok && strings.EqualFold(s, "AUTH") {
+				redact = append(redact, i+2)
+			}
+		}
+	}
+	redactionsMu.RLock()
+	redact = append(redact, redactions[commandName]...)
+	redactionsMu.RUnlock()
+
+	var result []interface{}
+	for _, i := range redact {
+		if i < 0 {
+			i += len(args)
+		}
+		if i < 0 || i >= len(args) {
+			continue
+		}
+		if result == nil {
+			result = append([]interface{}(nil), args...)
+		}
+		result[i] = redactedArg
+	}
+	if result == nil {
+		return args
+	}
+	return result
+}
+
 type loggingConn struct {
 	Conn
 	logger *log.Logger
@@ -102,7 +160,7 @@ func (c *loggingConn) print(method, commandName string, args []interface{}, repl
 	fmt.Fprintf(&buf, "%s%s(", c.prefix, method)
 	if method != "Receive" {
 		buf.WriteString(commandName)
-		for _, arg := range args {
+		for _, arg := range redactArgs(commandName, args) {
 			buf.WriteString(", ")
 			c.printValue(&buf, arg)
 		}
@@ -128,6 +186,12 @@ func (c *loggingConn) DoContext(ctx context.Context, commandName string, args ..
 	return reply, err
 }
 
+func (c *loggingConn) DoWithKey(routingKey string, commandName string, args ...interface{}) (interface{}, error) {
+	reply, err := DoWithKey(c.Conn, routingKey, commandName, args...)
+	c.print("DoWithKey", commandName, args, reply, err)
+	return reply, err
+}
+
 func (c *loggingConn) DoWithTimeout(timeout time.Duration, commandName string, args ...interface{}) (interface{}, error) {
 	reply, err := DoWithTimeout(c.Conn, timeout, commandName, args...)
 	c.print("DoWithTimeout", commandName, args, reply, err)
@@ -0,0 +1,54 @@
+// Copyright 2012 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package redis_test
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"testing"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoggingConnRedaction(t *testing.T) {
+	redis.RegisterRedaction("secret.set", 1)
+
+	tests := []struct {
+		name string
+		cmd  string
+		args []interface{}
+		want string
+	}{
+		{"auth", "AUTH", []interface{}{"pass"}, `Do(AUTH, "<redacted>")`},
+		{"auth user", "auth", []interface{}{"user", "pass"}, `Do(auth, "user", "<redacted>")`},
+		{"hello", "HELLO", []interface{}{3, "AUTH", "user", "pass", "SETNAME", "n"}, `Do(HELLO, 3, "AUTH", "user", "<redacted>", "SETNAME", "n")`},
+		{"registered", "SECRET.SET", []interface{}{"k", "v"}, `Do(SECRET.SET, "k", "<redacted>")`},
+		{"other", "SET", []interface{}{"k", "v"}, `Do(SET, "k", "v")`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := redis.Dial("", "", dialTestConn("+OK\r\n", io.Discard))
+			require.NoError(t, err)
+			var buf bytes.Buffer
+			lc := redis.NewLoggingConn(c, log.New(&buf, "", 0), "")
+			_, err = lc.Do(tt.cmd, tt.args...)
+			require.NoError(t, err)
+			require.Equal(t, tt.want+` -> ("OK", <nil>)`+"\n", buf.String())
+			require.NotEqual(t, "<redacted>", tt.args[len(tt.args)-1])
+		})
+	}
+}
@@ -0,0 +1,48 @@
+// Copyright 2012 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package redis
+
+import (
+	"context"
+	"time"
+)
+
+// MetricsRecorder records the duration of commands. Set the recorder for a
+// connection with the DialMetricsRecorder option.
+type MetricsRecorder interface {
+	// RecordCommand is called after each command issued with Do,
+	// DoContext or DoWithTimeout. The labels are the labels attached to
+	// the context passed to DoContext with WithMetricLabels or nil. The
+	// recorder must not modify the labels.
+	RecordCommand(commandName string, labels map[string]string, dur time.Duration, err error)
+}
+
+type metricLabelsKey struct{}
+
+// WithMetricLabels returns a copy of ctx with labels attached. The labels
+// are passed to the connection's metrics recorder for commands issued with
+// DoContext using the returned context. Use labels to bucket commands by
+// dimensions such as the operation name or the tenant. The application must
+// not modify labels after calling this function.
+func WithMetricLabels(ctx context.Context, labels map[string]string) context.Context {
+	return context.WithValue(ctx, metricLabelsKey{}, labels)
+}
+
+// MetricLabels returns the labels attached to ctx with WithMetricLabels or
+// nil if there are no labels.
+func MetricLabels(ctx context.Context) map[string]string {
+	labels, _ := ctx.Value(metricLabelsKey{}).(map[string]string)
+	return labels
+}
@@ -0,0 +1,111 @@
+// Copyright 2012 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package redis
+
+// Pipeline is a builder for a pipeline of commands. Add commands with Add
+// and send them to the server with Exec. The zero value is an empty
+// pipeline.
+type Pipeline struct {
+	cmds []PipeCommand
+}
+
+// PipelineOptions specifies how Exec handles error replies.
+type PipelineOptions struct {
+	// StopOnError returns the first error reply from Exec instead of
+	// collecting the replies to all commands.
+	//
+	// The commands are sent to the server before any reply is read, so
+	// the server executes the commands after a failed command. Exec reads
+	// and discards the remaining replies before returning to keep the
+	// connection usable.
+	StopOnError bool
+}
+
+// Add appends a command to the pipeline and returns the pipeline.
+func (p *Pipeline) Add(commandName string, args ...interface{}) *Pipeline {
+	p.cmds = append(p.cmds, PipeCommand{Name: commandName, Args: args})
+	return p
+}
+
+// Len returns the number of commands in the pipeline.
+func (p *Pipeline) Len() int {
+	return len(p.cmds)
+}
+
+// Reset removes all commands from the pipeline.
+func (p *Pipeline) Reset() {
+	p.cmds = p.cmds[:0]
+}
+
+// Exec sends the commands in the pipeline to the server and returns the
+// replies in command order. By default, Exec collects a reply for every
+// command. A failed command is recorded in the reply slice as the error
+// returned by Receive, usually an Error reply from the server, and the
+// returned error is set only when the connection fails. If
+// opts.StopOnError is set and a command fails, then Exec returns the
+// replies before the failed command and the error. The commands remain in
+// the pipeline after Exec returns.
+func (p *Pipeline) Exec(c Conn, opts PipelineOptions) ([]interface{}, error) {
+	if err := sendCommands(c, p.cmds); err != nil {
+		return nil, err
+	}
+	if err := c.Flush(); err != nil {
+		return nil, err
+	}
+
+	replies := make([]interface{}, 0, len(p.cmds))
+	var firstErr error
+	for range p.cmds {
+		reply, err := c.Receive()
+		if err != nil && c.Err() != nil {
+			return nil, err
+		}
+		if firstErr != nil {
+			continue
+		}
+		if err != nil {
+			// The connection is usable, so the error belongs to this
+			// command only.
+			if opts.StopOnError {
+				firstErr = err
+				continue
+			}
+			reply = err
+		}
+		replies = append(replies, reply)
+	}
+	return replies, firstErr
+}
+
+// sendCommands sends cmds to c without flushing. If a Send fails, then
+// sendCommands flushes the commands sent before the failed command and
+// discards their replies to keep the connection usable.
+func sendCommands(c Conn, cmds []PipeCommand) error {
+	for i, cmd := range cmds {
+		err := c.Send(cmd.Name, cmd.Args...)
+		if err == nil {
+			continue
+		}
+		if i > 0 && c.Err() == nil && c.Flush() == nil {
+			for ; i > 0; i-- {
+				if _, rerr := c.Receive(); rerr != nil && c.Err() != nil {
+					break
+				}
+			}
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+// Copyright 2012 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package redis_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPipelineExec(t *testing.T) {
+	var p redis.Pipeline
+	p.Add("SET", "a", 1).Add("INCR", "b").Add("GET", "a")
+	require.Equal(t, 3, p.Len())
+
+	const resp = "+OK\r\n-ERR not an integer\r\n$1\r\n1\r\n"
+
+	t.Run("collect", func(t *testing.T) {
+		var buf bytes.Buffer
+		c, err := redis.Dial("", "", dialTestConn(resp, &buf))
+		require.NoError(t, err)
+		replies, err := p.Exec(c, redis.PipelineOptions{})
+		require.NoError(t, err)
+		require.Equal(t, []interface{}{"OK", redis.Error("ERR not an integer"), []byte("1")}, replies)
+		require.Equal(t, "*3\r\n$3\r\nSET\r\n$1\r\na\r\n$1\r\n1\r\n*2\r\n$4\r\nINCR\r\n$1\r\nb\r\n*2\r\n$3\r\nGET\r\n$1\r\na\r\n", buf.String())
+	})
+
+	t.Run("stop on error", func(t *testing.T) {
+		c, err := redis.Dial("", "", dialTestConn(resp+"+PONG\r\n", io.Discard))
+		require.NoError(t, err)
+		replies, err := p.Exec(c, redis.PipelineOptions{StopOnError: true})
+		require.Equal(t, redis.Error("ERR not an integer"), err)
+		require.Equal(t, []interface{}{"OK"}, replies)
+
+		// The remaining reply was drained.
+		s, err := redis.String(c.Do("PING"))
+		require.NoError(t, err)
+		require.Equal(t, "PONG", s)
+	})
+
+	t.Run("collect wrapped error", func(t *testing.T) {
+		c, err := redis.Dial("", "", dialTestConn(resp, io.Discard))
+		require.NoError(t, err)
+		replies, err := p.Exec(wrapReceiveConn{c}, redis.PipelineOptions{})
+		require.NoError(t, err)
+		require.Len(t, replies, 3)
+		require.Equal(t, "OK", replies[0])
+		require.Equal(t, &redis.CommandError{Command: "RECEIVE", Err: redis.Error("ERR not an integer")}, replies[1])
+		require.Equal(t, []byte("1"), replies[2])
+	})
+
+	t.Run("send error", func(t *testing.T) {
+		var buf bytes.Buffer
+		c, err := redis.Dial("", "", dialTestConn("+OK\r\n+PONG\r\n", &buf), redis.DialMaxPendingReplies(1))
+		require.NoError(t, err)
+		_, err = p.Exec(c, redis.PipelineOptions{})
+		require.Equal(t, redis.ErrMaxPendingReplies, err)
+		require.Equal(t, "*3\r\n$3\r\nSET\r\n$1\r\na\r\n$1\r\n1\r\n", buf.String())
+
+		// The reply to the command sent before the failure was drained.
+		s, err := redis.String(c.Do("PING"))
+		require.NoError(t, err)
+		require.Equal(t, "PONG", s)
+	})
+
+	p.Reset()
+	require.Equal(t, 0, p.Len())
+}
+
+// wrapReceiveConn wraps the errors returned by Receive in a CommandError.
+type wrapReceiveConn struct{ redis.Conn }
+
+func (c wrapReceiveConn) Receive() (interface{}, error) {
+	reply, err := c.Conn.Receive()
+	if err != nil {
+		err = &redis.CommandError{Command: "RECEIVE", Err: err}
+	}
+	return reply, err
+}
@@ -20,6 +20,7 @@ import (
 	"crypto/rand"
 	"crypto/sha1"
 	"errors"
+	"fmt"
 	"io"
 	"strconv"
 	"sync"
@@ -29,6 +30,8 @@ import (
 var (
 	_ ConnWithTimeout = (*activeConn)(nil)
 	_ ConnWithTimeout = (*errorConn)(nil)
+	_ ConnWithKey     = (*activeConn)(nil)
+	_ ConnWithKey     = (*errorConn)(nil)
 )
 
 var nowFunc = time.Now // for testing
@@ -38,10 +41,76 @@ var nowFunc = time.Now // for testing
 // pool has been reached.
 var ErrPoolExhausted = errors.New("redigo: connection pool exhausted")
 
+// ErrRateLimited is returned from a pool connection method when the pool's
+// RateLimiter rejects getting the connection.
+var ErrRateLimited = errors.New("redigo: rate limited")
+
+// RateLimiter limits the rate at which connections are borrowed from a
+// Pool. The Wait method of *rate.Limiter in golang.org/x/time/rate satisfies
+// the interface.
+type RateLimiter interface {
+	// Wait blocks until the limiter permits an event or returns an error if
+	// the event is rejected or the context is done.
+	Wait(ctx context.Context) error
+}
+
 var (
 	errConnClosed = errors.New("redigo: connection closed")
 )
 
+// ConnError is returned from a pool connection's Do and Receive methods when
+// a network error leaves the underlying connection unusable. The pool
+// discards the connection when it is closed.
+type ConnError struct {
+	// Err is the error returned by the underlying connection.
+	Err error
+
+	// Written reports whether the command was completely written to the
+	// server before the error occurred. When Written is false, the server did
+	// not receive the command and the command can be safely retried on
+	// another connection.
+	Written bool
+}
+
+func (err *ConnError) Error() string {
+	if err.Written {
+		return "redigo: connection error after command was written: " + err.Err.Error()
+	}
+	return "redigo: connection error before command was written: " + err.Err.Error()
+}
+
+func (err *ConnError) Unwrap() error { return err.Err }
+
+// wrapConnError returns err wrapped in a ConnError if err made c unusable.
+// Server errors and context errors are returned as is.
+func wrapConnError(c Conn, err error, written bool) error {
+	if err == nil || c.Err() == nil {
+		return err
+	}
+	var e Error
+	if errors.As(err, &e) {
+		return err
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	var ce *ConnError
+	if errors.As(err, &ce) {
+		return err
+	}
+	return &ConnError{Err: err, Written: written}
+}
+
+// commandWritten reports whether the last command sent with Do on c was
+// written to the server. If c cannot report this, then commandWritten
+// returns true because that is the safe assumption for retries.
+func commandWritten(c Conn) bool {
+	if cw, ok := c.(interface{ commandWritten() bool }); ok {
+		return cw.commandWritten()
+	}
+	return true
+}
+
 // Pool maintains a pool of connections. The application calls the Get method
 // to get a connection from the pool and the connection's Close method to
 // return the connection's resources to the pool.
@@ -159,14 +228,53 @@ type Pool struct {
 	// the pool does not close connections based on age.
 	MaxConnLifetime time.Duration
 
+	// Maximum number of connections the pool dials concurrently. Calls to
+	// Get that need a new connection when the limit is reached wait for an
+	// in-flight dial to complete and use an idle connection if one is
+	// available at that time. When zero, there is no limit on concurrent
+	// dials.
+	MaxConcurrentDials int
+
+	// CommandTimeout bounds the time for each Do and DoContext call on
+	// connections from the pool. The timeout is applied with a context
+	// deadline. When DoContext is called with a context that has an earlier
+	// deadline, the earlier deadline is used. If the value is zero, then
+	// commands are bounded only by the connection's read and write timeouts.
+	// The connections returned by Dial or DialContext must satisfy the
+	// ConnWithContext interface for the timeout to have an effect.
+	CommandTimeout time.Duration
+
+	// RateLimiter is consulted before each connection is borrowed from the
+	// pool with Get or GetContext. If the limiter rejects the connection, then
+	// the error returned from getting the connection wraps ErrRateLimited. If
+	// the context is done while waiting, then the context error is returned
+	// as is. If RateLimiter is nil, then the pool does not limit the rate.
+	RateLimiter RateLimiter
+
+	// IdleKeepAlive is the maximum time that a connection stays idle in the
+	// pool without a command. The pool sends PING to connections that are
+	// idle for longer than half of IdleKeepAlive to prevent the server from
+	// closing the connections with the server's timeout setting. Set the
+	// value to less than the server timeout. Connections in use are not
+	// pinged and connections that fail the PING are closed. The PING times
+	// out after half of IdleKeepAlive. Idle connections are checked at most
+	// once per millisecond. If the value is zero, then the pool does not
+	// ping idle connections.
+	IdleKeepAlive time.Duration
+
+	keepAliveOnce sync.Once
+
 	mu           sync.Mutex    // mu protects the following fields
 	closed       bool          // set to true when the pool is closed.
 	active       int           // the number of open connections in the pool
 	initOnce     sync.Once     // the init ch once func
 	ch           chan struct{} // limits open connections when p.Wait is true
 	idle         idleList      // idle connections
+	dialSem      chan struct{} // limits concurrent dials when p.MaxConcurrentDials > 0
 	waitCount    int64         // total number of connections waited for.
 	waitDuration time.Duration // total time waited for new connections.
+	keepAlive    chan struct{} // closed to stop the keepalive goroutine
+	keepAliveEnd chan struct{} // closed when the keepalive goroutine exits
 }
 
 // NewPool creates a new pool.
@@ -196,6 +304,15 @@ func (p *Pool) Get() Conn {
 // If the function completes without error, then the application must close the
 // returned connection.
 func (p *Pool) GetContext(ctx context.Context) (Conn, error) {
+	if p.RateLimiter != nil {
+		if err := p.RateLimiter.Wait(ctx); err != nil {
+			if !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) {
+				err = fmt.Errorf("%w: %v", ErrRateLimited, err)
+			}
+			return errorConn{err}, err
+		}
+	}
+
 	// Wait until there is a vacant connection in the pool.
 	waited, err := p.waitVacantConn(ctx)
 	if err != nil {
@@ -222,36 +339,80 @@ func (p *Pool) GetContext(ctx context.Context) (Conn, error) {
 		}
 	}
 
-	// Get idle connection from the front of idle list.
-	for p.idle.front != nil {
-		pc := p.idle.front
-		p.idle.popFront()
-		p.mu.Unlock()
-		if (p.TestOnBorrow == nil || p.TestOnBorrow(pc.c, pc.t) == nil) &&
-			(p.MaxConnLifetime == 0 || nowFunc().Sub(pc.created) < p.MaxConnLifetime) {
-			return &activeConn{p: p, pc: pc}, nil
+	var dialing bool
+	for {
+		// Get idle connection from the front of idle list.
+		for p.idle.front != nil {
+			pc := p.idle.front
+			p.idle.popFront()
+			p.mu.Unlock()
+			// Check the idle timeout again because the prune above runs
+			// once before waiting for a dial slot. Connections at the back
+			// of the list can pass the idle timeout during the wait.
+			if (p.IdleTimeout == 0 || !pc.t.Add(p.IdleTimeout).Before(nowFunc())) &&
+				(p.TestOnBorrow == nil || p.TestOnBorrow(pc.c, pc.t) == nil) &&
+				(p.MaxConnLifetime == 0 || nowFunc().Sub(pc.created) < p.MaxConnLifetime) {
+				if dialing {
+					<-p.dialSem
+				}
+				return &activeConn{p: p, pc: pc}, nil
+			}
+			pc.c.Close()
+			p.mu.Lock()
+			p.active--
 		}
-		pc.c.Close()
-		p.mu.Lock()
-		p.active--
-	}
 
-	// Check for pool closed before dialing a new connection.
-	if p.closed {
-		p.mu.Unlock()
-		err := errors.New("redigo: get on closed pool")
-		return errorConn{err}, err
-	}
+		// Check for pool closed before dialing a new connection.
+		if p.closed {
+			p.mu.Unlock()
+			if dialing {
+				<-p.dialSem
+			}
+			err := errors.New("redigo: get on closed pool")
+			return errorConn{err}, err
+		}
 
-	// Handle limit for p.Wait == false.
-	if !p.Wait && p.MaxActive > 0 && p.active >= p.MaxActive {
+		// Handle limit for p.Wait == false.
+		if !p.Wait && p.MaxActive > 0 && p.active >= p.MaxActive {
+			p.mu.Unlock()
+			if dialing {
+				<-p.dialSem
+			}
+			return errorConn{ErrPoolExhausted}, ErrPoolExhausted
+		}
+
+		if dialing || p.MaxConcurrentDials <= 0 {
+			break
+		}
+
+		// Wait for a dial slot and check the idle list again because
+		// the connection from another dial may have been returned to the
+		// pool while waiting.
+		if p.dialSem == nil {
+			p.dialSem = make(chan struct{}, p.MaxConcurrentDials)
+		}
+		sem := p.dialSem
 		p.mu.Unlock()
-		return errorConn{ErrPoolExhausted}, ErrPoolExhausted
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			p.mu.Lock()
+			if p.ch != nil && !p.closed {
+				p.ch <- struct{}{}
+			}
+			p.mu.Unlock()
+			return errorConn{ctx.Err()}, ctx.Err()
+		}
+		dialing = true
+		p.mu.Lock()
 	}
 
 	p.active++
 	p.mu.Unlock()
 	c, err := p.dial(ctx)
+	if dialing {
+		<-p.dialSem
+	}
 	if err != nil {
 		p.mu.Lock()
 		p.active--
@@ -264,6 +425,87 @@ func (p *Pool) GetContext(ctx context.Context) (Conn, error) {
 	return &activeConn{p: p, pc: &poolConn{c: c, created: nowFunc()}}, nil
 }
 
+// GetWithSetup gets a connection using the provided context and runs setup
+// on the connection before returning it. Use setup for commands that depend
+// on the caller such as SELECT. If setup returns an error, then the
+// connection is closed and another connection is tried. If setup fails on a
+// newly dialed connection, then GetWithSetup returns the error from setup.
+//
+// If the function completes without error, then the application must close the
+// returned connection.
+func (p *Pool) GetWithSetup(ctx context.Context, setup func(Conn) error) (Conn, error) {
+	for {
+		c, err := p.GetContext(ctx)
+		if err != nil {
+			return c, err
+		}
+		ac := c.(*activeConn)
+		dialed := ac.pc.t.IsZero()
+		err = setup(c)
+		if err == nil {
+			return c, nil
+		}
+		ac.discard()
+		if dialed {
+			return errorConn{err}, err
+		}
+	}
+}
+
+// GetForDB gets a connection using the provided context and selects the
+// database db on the connection. The SELECT command is skipped when the
+// connection is known to have db selected, for example when the connection
+// was returned to the pool after selecting db. Like GetWithSetup, GetForDB
+// tries another connection if SELECT fails on an idle connection.
+//
+// If the function completes without error, then the application must close the
+// returned connection.
+func (p *Pool) GetForDB(ctx context.Context, db int) (Conn, error) {
+	return p.GetWithSetup(ctx, func(c Conn) error {
+		if cdb, ok := c.(*activeConn).pc.c.(interface{ selectedDB() (int, bool) }); ok {
+			if current, ok := cdb.selectedDB(); ok && current == db {
+				return nil
+			}
+		}
+		_, err := c.Do("SELECT", db)
+		return err
+	})
+}
+
+// ErrReplicasTimeout is returned by WriteThenWaitReplicas when the timeout
+// elapses before the requested number of replicas acknowledge the writes.
+var ErrReplicasTimeout = errors.New("redigo: WAIT timed out before the requested replicas acknowledged the writes")
+
+// WriteThenWaitReplicas gets a connection, calls write with the connection
+// and then sends WAIT on the same connection to block until numReplicas
+// replicas acknowledge the writes or timeout elapses. The write function
+// should use Send to pipeline the writes with WAIT in a single round trip.
+// If a pipelined write fails, then WriteThenWaitReplicas returns the error.
+// If fewer than numReplicas replicas acknowledge the writes, then
+// WriteThenWaitReplicas returns ErrReplicasTimeout. A zero timeout blocks
+// indefinitely.
+//
+// WAIT does not make the writes strongly consistent, but after a nil return
+// reads from the acknowledging replicas observe the writes.
+func (p *Pool) WriteThenWaitReplicas(ctx context.Context, numReplicas int, timeout time.Duration, write func(Conn) error) error {
+	c, err := p.GetContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	if err := write(c); err != nil {
+		return err
+	}
+	n, err := Int(DoContext(c, ctx, "WAIT", numReplicas, int64(timeout/time.Millisecond)))
+	if err != nil {
+		return err
+	}
+	if n < numReplicas {
+		return ErrReplicasTimeout
+	}
+	return nil
+}
+
 // PoolStats contains pool statistics.
 type PoolStats struct {
 	// ActiveCount is the number of connections in the pool. The count includes
@@ -320,6 +562,10 @@ func (p *Pool) Close() error {
 		return nil
 	}
 	p.closed = true
+	keepAliveEnd := p.keepAliveEnd
+	if p.keepAlive != nil {
+		close(p.keepAlive)
+	}
 	p.active -= p.idle.count
 	pc := p.idle.front
 	p.idle.count = 0
@@ -331,6 +577,9 @@ func (p *Pool) Close() error {
 	for ; pc != nil; pc = pc.next {
 		pc.c.Close()
 	}
+	if keepAliveEnd != nil {
+		<-keepAliveEnd
+	}
 	return nil
 }
 
@@ -400,6 +649,9 @@ func (p *Pool) dial(ctx context.Context) (Conn, error) {
 }
 
 func (p *Pool) put(pc *poolConn, forceClose bool) error {
+	if p.IdleKeepAlive > 0 {
+		p.keepAliveOnce.Do(p.startKeepAlive)
+	}
 	p.mu.Lock()
 	if !p.closed && !forceClose {
 		pc.t = nowFunc()
@@ -426,6 +678,111 @@ func (p *Pool) put(pc *poolConn, forceClose bool) error {
 	return nil
 }
 
+// minKeepAliveInterval is the minimum interval between keepalive passes.
+const minKeepAliveInterval = time.Millisecond
+
+// startKeepAlive starts the goroutine that pings idle connections.
+func (p *Pool) startKeepAlive() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return
+	}
+	interval := p.IdleKeepAlive / 2
+	if interval < minKeepAliveInterval {
+		interval = minKeepAliveInterval
+	}
+	p.keepAlive = make(chan struct{})
+	p.keepAliveEnd = make(chan struct{})
+	go p.keepAliveLoop(interval, p.keepAlive, p.keepAliveEnd)
+}
+
+func (p *Pool) keepAliveLoop(interval time.Duration, done, end chan struct{}) {
+	defer close(end)
+	if p.Wait && p.MaxActive > 0 {
+		p.lazyInit()
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-t.C:
+		}
+
+		// Remove the connections to ping from the idle list so that the
+		// connections are not borrowed while pinging. The connections are
+		// not counted as active while pinging so that Get dials instead of
+		// returning ErrPoolExhausted. When p.Wait is true, each connection
+		// holds a token from p.ch while pinging so that Get waits for the
+		// connection instead of dialing past MaxActive.
+		var stale []*poolConn
+		p.mu.Lock()
+		now := nowFunc()
+	collect:
+		for pc := p.idle.front; pc != nil; {
+			next := pc.next
+			last := pc.t
+			if pc.pinged.After(last) {
+				last = pc.pinged
+			}
+			if now.Sub(last) >= interval {
+				if p.ch != nil {
+					select {
+					case <-p.ch:
+					default:
+						break collect
+					}
+				}
+				p.idle.remove(pc)
+				p.active--
+				stale = append(stale, pc)
+			}
+			pc = next
+		}
+		p.mu.Unlock()
+
+		for _, pc := range stale {
+			err := keepAlivePing(pc.c, interval)
+			pc.pinged = nowFunc()
+			p.keepAliveDone(pc, err == nil)
+		}
+	}
+}
+
+// keepAlivePing sends PING to c. The PING is bounded by timeout when c
+// supports ConnWithTimeout so that a stalled server does not block the
+// keepalive goroutine and Close.
+func keepAlivePing(c Conn, timeout time.Duration) error {
+	if cwt, ok := c.(ConnWithTimeout); ok {
+		_, err := cwt.DoWithTimeout(timeout, "PING")
+		return err
+	}
+	_, err := c.Do("PING")
+	return err
+}
+
+// keepAliveDone returns a pinged connection to the back of the idle list,
+// which holds the connections idle the longest. The connection is closed
+// if the PING failed, the pool is closed or full, or the connections dialed
+// while pinging reached MaxActive.
+func (p *Pool) keepAliveDone(pc *poolConn, ok bool) {
+	p.mu.Lock()
+	if ok && !p.closed && p.idle.count < p.MaxIdle && (p.MaxActive <= 0 || p.active < p.MaxActive) {
+		p.active++
+		p.idle.pushBack(pc)
+		pc = nil
+	}
+	if p.ch != nil && !p.closed {
+		p.ch <- struct{}{}
+	}
+	p.mu.Unlock()
+	if pc != nil {
+		pc.c.Close()
+	}
+}
+
 type activeConn struct {
 	p     *Pool
 	pc    *poolConn
@@ -504,6 +861,17 @@ func (ac *activeConn) Close() (err error) {
 	)
 }
 
+// discard closes the underlying connection without returning it to the
+// idle list.
+func (ac *activeConn) discard() {
+	pc := ac.pc
+	if pc == nil {
+		return
+	}
+	ac.pc = nil
+	ac.p.put(pc, true) // nolint: errcheck
+}
+
 func (ac *activeConn) Err() error {
 	pc := ac.pc
 	if pc == nil {
@@ -523,7 +891,13 @@ func (ac *activeConn) DoContext(ctx context.Context, commandName string, args ..
 	}
 	ci := lookupCommandInfo(commandName)
 	ac.state = (ac.state | ci.Set) &^ ci.Clear
-	return cwt.DoContext(ctx, commandName, args...)
+	if ac.p.CommandTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, ac.p.CommandTimeout)
+		defer cancel()
+	}
+	reply, err = cwt.DoContext(ctx, commandName, args...)
+	return reply, wrapConnError(pc.c, err, commandWritten(pc.c))
 }
 
 func (ac *activeConn) Do(commandName string, args ...interface{}) (reply interface{}, err error) {
@@ -533,7 +907,25 @@ func (ac *activeConn) Do(commandName string, args ...interface{}) (reply interfa
 	}
 	ci := lookupCommandInfo(commandName)
 	ac.state = (ac.state | ci.Set) &^ ci.Clear
-	return pc.c.Do(commandName, args...)
+	if cwc, ok := pc.c.(ConnWithContext); ok && ac.p.CommandTimeout > 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), ac.p.CommandTimeout)
+		defer cancel()
+		reply, err = cwc.DoContext(ctx, commandName, args...)
+	} else {
+		reply, err = pc.c.Do(commandName, args...)
+	}
+	return reply, wrapConnError(pc.c, err, commandWritten(pc.c))
+}
+
+func (ac *activeConn) DoWithKey(routingKey string, commandName string, args ...interface{}) (reply interface{}, err error) {
+	pc := ac.pc
+	if pc == nil {
+		return nil, errConnClosed
+	}
+	ci := lookupCommandInfo(commandName)
+	ac.state = (ac.state | ci.Set) &^ ci.Clear
+	reply, err = DoWithKey(pc.c, routingKey, commandName, args...)
+	return reply, wrapConnError(pc.c, err, commandWritten(pc.c))
 }
 
 func (ac *activeConn) DoWithTimeout(timeout time.Duration, commandName string, args ...interface{}) (reply interface{}, err error) {
@@ -547,7 +939,58 @@ func (ac *activeConn) DoWithTimeout(timeout time.Duration, commandName string, a
 	}
 	ci := lookupCommandInfo(commandName)
 	ac.state = (ac.state | ci.Set) &^ ci.Clear
-	return cwt.DoWithTimeout(timeout, commandName, args...)
+	reply, err = cwt.DoWithTimeout(timeout, commandName, args...)
+	return reply, wrapConnError(pc.c, err, commandWritten(pc.c))
+}
+
+func (ac *activeConn) DoRaw(resp []byte) (reply interface{}, err error) {
+	pc := ac.pc
+	if pc == nil {
+		return nil, errConnClosed
+	}
+	reply, err = DoRaw(pc.c, resp)
+	return reply, wrapConnError(pc.c, err, commandWritten(pc.c))
+}
+
+func (ac *activeConn) DoEach(fn func(index int, value []byte) error, commandName string, args ...interface{}) error {
+	pc := ac.pc
+	if pc == nil {
+		return errConnClosed
+	}
+	err := DoEach(pc.c, fn, commandName, args...)
+	return wrapConnError(pc.c, err, true)
+}
+
+func (ac *activeConn) Drain(n int) error {
+	pc := ac.pc
+	if pc == nil {
+		return errConnClosed
+	}
+	return Drain(pc.c, n)
+}
+
+func (ac *activeConn) DrainAll(timeout time.Duration) (int, error) {
+	pc := ac.pc
+	if pc == nil {
+		return 0, errConnClosed
+	}
+	return DrainAll(pc.c, timeout)
+}
+
+func (ac *activeConn) SetCommandDeadline(t time.Time) error {
+	pc := ac.pc
+	if pc == nil {
+		return errConnClosed
+	}
+	return SetCommandDeadline(pc.c, t)
+}
+
+func (ac *activeConn) ClientID() (int64, error) {
+	pc := ac.pc
+	if pc == nil {
+		return 0, errConnClosed
+	}
+	return ClientID(pc.c)
 }
 
 func (ac *activeConn) Send(commandName string, args ...interface{}) error {
@@ -573,7 +1016,8 @@ func (ac *activeConn) Receive() (reply interface{}, err error) {
 	if pc == nil {
 		return nil, errConnClosed
 	}
-	return pc.c.Receive()
+	reply, err = pc.c.Receive()
+	return reply, wrapConnError(pc.c, err, true)
 }
 
 func (ac *activeConn) ReceiveContext(ctx context.Context) (reply interface{}, err error) {
@@ -585,7 +1029,8 @@ func (ac *activeConn) ReceiveContext(ctx context.Context) (reply interface{}, er
 	if !ok {
 		return nil, errContextNotSupported
 	}
-	return cwt.ReceiveContext(ctx)
+	reply, err = cwt.ReceiveContext(ctx)
+	return reply, wrapConnError(pc.c, err, true)
 }
 
 func (ac *activeConn) ReceiveWithTimeout(timeout time.Duration) (reply interface{}, err error) {
@@ -597,7 +1042,8 @@ func (ac *activeConn) ReceiveWithTimeout(timeout time.Duration) (reply interface
 	if !ok {
 		return nil, errTimeoutNotSupported
 	}
-	return cwt.ReceiveWithTimeout(timeout)
+	reply, err = cwt.ReceiveWithTimeout(timeout)
+	return reply, wrapConnError(pc.c, err, true)
 }
 
 type errorConn struct{ err error }
@@ -609,6 +1055,16 @@ func (ec errorConn) DoContext(context.Context, string, ...interface{}) (interfac
 func (ec errorConn) DoWithTimeout(time.Duration, string, ...interface{}) (interface{}, error) {
 	return nil, ec.err
 }
+func (ec errorConn) DoWithKey(string, string, ...interface{}) (interface{}, error) {
+	return nil, ec.err
+}
+func (ec errorConn) DoEach(func(int, []byte) error, string, ...interface{}) error {
+	return ec.err
+}
+func (ec errorConn) DoRaw([]byte) (interface{}, error)                     { return nil, ec.err }
+func (ec errorConn) Drain(int) error                                       { return ec.err }
+func (ec errorConn) DrainAll(time.Duration) (int, error)                   { return 0, ec.err }
+func (ec errorConn) SetCommandDeadline(time.Time) error                    { return ec.err }
 func (ec errorConn) Send(string, ...interface{}) error                     { return ec.err }
 func (ec errorConn) Err() error                                            { return ec.err }
 func (ec errorConn) Close() error                                          { return nil }
@@ -626,6 +1082,7 @@ type poolConn struct {
 	c          Conn
 	t          time.Time
 	created    time.Time
+	pinged     time.Time // time of the last keepalive PING
 	next, prev *poolConn
 }
 
@@ -641,6 +1098,32 @@ func (l *idleList) pushFront(pc *poolConn) {
 	l.count++
 }
 
+func (l *idleList) pushBack(pc *poolConn) {
+	pc.next = nil
+	pc.prev = l.back
+	if l.count == 0 {
+		l.front = pc
+	} else {
+		l.back.next = pc
+	}
+	l.back = pc
+	l.count++
+}
+
+func (l *idleList) remove(pc *poolConn) {
+	switch pc {
+	case l.front:
+		l.popFront()
+	case l.back:
+		l.popBack()
+	default:
+		pc.prev.next = pc.next
+		pc.next.prev = pc.prev
+		pc.next, pc.prev = nil, nil
+		l.count--
+	}
+}
+
 func (l *idleList) popFront() {
 	pc := l.front
 	l.count--
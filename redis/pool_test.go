@@ -15,12 +15,15 @@
 package redis_test
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"io"
 	"net"
 	"reflect"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -208,6 +211,314 @@ func TestPoolError(t *testing.T) {
 	d.check(".", p, 2, 0, 0)
 }
 
+type errWriter struct{}
+
+func (errWriter) Write(p []byte) (int, error) { return 0, io.ErrClosedPipe }
+
+func TestPoolConnError(t *testing.T) {
+	tests := []struct {
+		name    string
+		w       io.Writer
+		written bool
+	}{
+		{"read", io.Discard, true},
+		{"write", errWriter{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &redis.Pool{
+				MaxIdle: 1,
+				Dial: func() (redis.Conn, error) {
+					return redis.Dial("", "", dialTestConn("", tt.w))
+				},
+			}
+			defer p.Close()
+
+			c := p.Get()
+			_, err := c.Do("PING")
+			var ce *redis.ConnError
+			require.True(t, errors.As(err, &ce), "expected ConnError, got %v", err)
+			require.Equal(t, tt.written, ce.Written)
+			require.NoError(t, c.Close())
+			require.Equal(t, 0, p.IdleCount())
+			require.Equal(t, 0, p.ActiveCount())
+		})
+	}
+}
+
+func TestPoolMaxConcurrentDials(t *testing.T) {
+	var (
+		mu                  sync.Mutex
+		dialing, maxDialing int
+	)
+	p := &redis.Pool{
+		MaxIdle:            testGoRoutines,
+		MaxConcurrentDials: 2,
+		Dial: func() (redis.Conn, error) {
+			mu.Lock()
+			dialing++
+			if dialing > maxDialing {
+				maxDialing = dialing
+			}
+			mu.Unlock()
+			time.Sleep(10 * time.Millisecond)
+			mu.Lock()
+			dialing--
+			mu.Unlock()
+			return redis.Dial("", "", dialTestConn("", io.Discard))
+		},
+	}
+	defer p.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < testGoRoutines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c := p.Get()
+			require.NoError(t, c.Err())
+			c.Close()
+		}()
+	}
+	wg.Wait()
+
+	if maxDialing > 2 {
+		t.Errorf("max concurrent dials = %d, want at most 2", maxDialing)
+	}
+	if n := p.ActiveCount(); n >= testGoRoutines {
+		t.Errorf("active = %d, want less than %d", n, testGoRoutines)
+	}
+}
+
+// dialSilent dials a connection to a server that never replies.
+func dialSilent() (redis.Conn, error) {
+	client, server := net.Pipe()
+	go io.Copy(io.Discard, server) // nolint: errcheck
+	return redis.NewConn(client, 0, 0), nil
+}
+
+func TestPoolCommandTimeout(t *testing.T) {
+	tests := []struct {
+		name           string
+		commandTimeout time.Duration
+		ctxTimeout     time.Duration
+	}{
+		{"pool", 10 * time.Millisecond, 0},
+		{"pool with context", 10 * time.Millisecond, time.Hour},
+		{"context", time.Hour, 10 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &redis.Pool{
+				CommandTimeout: tt.commandTimeout,
+				Dial:           dialSilent,
+			}
+			defer p.Close()
+
+			c := p.Get()
+			defer c.Close()
+			var err error
+			start := time.Now()
+			if tt.ctxTimeout == 0 {
+				_, err = c.Do("PING")
+			} else {
+				ctx, cancel := context.WithTimeout(context.Background(), tt.ctxTimeout)
+				defer cancel()
+				_, err = redis.DoContext(c, ctx, "PING")
+			}
+			require.Error(t, err)
+			require.Less(t, int64(time.Since(start)), int64(time.Second))
+		})
+	}
+}
+
+func TestPoolGetWithSetup(t *testing.T) {
+	dialed := 0
+	p := &redis.Pool{
+		MaxIdle: 2,
+		Dial: func() (redis.Conn, error) {
+			dialed++
+			return redis.Dial("", "", dialTestConn("", io.Discard))
+		},
+	}
+	defer p.Close()
+
+	// Put an idle connection in the pool.
+	c := p.Get()
+	require.NoError(t, c.Close())
+	require.Equal(t, 1, p.IdleCount())
+
+	setupErr := errors.New("setup failed")
+	calls := 0
+	c, err := p.GetWithSetup(context.Background(), func(c redis.Conn) error {
+		calls++
+		if calls == 1 {
+			return setupErr
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, calls)
+	require.Equal(t, 2, dialed)
+	require.Equal(t, 1, p.ActiveCount())
+	require.NoError(t, c.Close())
+
+	// Setup failing on a new connection returns the error.
+	p.Close()
+	p = &redis.Pool{Dial: dialSilent}
+	defer p.Close()
+	_, err = p.GetWithSetup(context.Background(), func(c redis.Conn) error { return setupErr })
+	require.ErrorIs(t, err, setupErr)
+	require.Equal(t, 0, p.ActiveCount())
+}
+
+func TestPoolGetForDB(t *testing.T) {
+	var buf bytes.Buffer
+	p := &redis.Pool{
+		MaxIdle: 1,
+		Dial: func() (redis.Conn, error) {
+			return redis.Dial("", "", dialTestConn("+OK\r\n+OK\r\n+OK\r\n", &buf))
+		},
+	}
+	defer p.Close()
+	ctx := context.Background()
+	const select3 = "*2\r\n$6\r\nSELECT\r\n$1\r\n3\r\n"
+
+	// A new connection is on database 0.
+	c, err := p.GetForDB(ctx, 0)
+	require.NoError(t, err)
+	require.NoError(t, c.Close())
+	require.Equal(t, "", buf.String())
+
+	c, err = p.GetForDB(ctx, 3)
+	require.NoError(t, err)
+	require.NoError(t, c.Close())
+	require.Equal(t, select3, buf.String())
+
+	// The connection is already on database 3.
+	c, err = p.GetForDB(ctx, 3)
+	require.NoError(t, err)
+	require.Equal(t, select3, buf.String())
+
+	// The database is not known after a pipelined SELECT.
+	require.NoError(t, c.Send("SELECT", 3))
+	_, err = c.Do("")
+	require.NoError(t, err)
+	require.NoError(t, c.Close())
+	buf.Reset()
+	c, err = p.GetForDB(ctx, 3)
+	require.NoError(t, err)
+	require.NoError(t, c.Close())
+	require.Equal(t, select3, buf.String())
+}
+
+func TestPoolWriteThenWaitReplicas(t *testing.T) {
+	var buf bytes.Buffer
+	dialed := 0
+	p := &redis.Pool{
+		MaxIdle: 1,
+		Dial: func() (redis.Conn, error) {
+			dialed++
+			return redis.Dial("", "", dialTestConn("+OK\r\n+OK\r\n:2\r\n+OK\r\n:1\r\n-ERR x\r\n:2\r\n", &buf))
+		},
+	}
+	defer p.Close()
+	ctx := context.Background()
+	write := func(c redis.Conn) error {
+		if err := c.Send("SET", "a", "1"); err != nil {
+			return err
+		}
+		return c.Send("SET", "b", "2")
+	}
+
+	err := p.WriteThenWaitReplicas(ctx, 2, 100*time.Millisecond, write)
+	require.NoError(t, err)
+	require.Equal(t, 1, dialed)
+	require.Equal(t, "*3\r\n$3\r\nSET\r\n$1\r\na\r\n$1\r\n1\r\n"+
+		"*3\r\n$3\r\nSET\r\n$1\r\nb\r\n$1\r\n2\r\n"+
+		"*3\r\n$4\r\nWAIT\r\n$1\r\n2\r\n$3\r\n100\r\n", buf.String())
+
+	err = p.WriteThenWaitReplicas(ctx, 2, 100*time.Millisecond, func(c redis.Conn) error {
+		return c.Send("SET", "a", "1")
+	})
+	require.Equal(t, redis.ErrReplicasTimeout, err)
+
+	err = p.WriteThenWaitReplicas(ctx, 2, 100*time.Millisecond, func(c redis.Conn) error {
+		return c.Send("INCR", "a")
+	})
+	require.EqualError(t, err, "ERR x")
+	require.Equal(t, 1, dialed, "commands run on one connection")
+
+	errWrite := errors.New("write")
+	err = p.WriteThenWaitReplicas(ctx, 2, 0, func(c redis.Conn) error { return errWrite })
+	require.Equal(t, errWrite, err)
+}
+
+// countLimiter permits n events and rejects the remaining events.
+type countLimiter struct{ n int }
+
+func (l *countLimiter) Wait(ctx context.Context) error {
+	if l.n == 0 {
+		return errors.New("limit reached")
+	}
+	l.n--
+	return nil
+}
+
+func TestPoolRateLimiter(t *testing.T) {
+	p := &redis.Pool{
+		MaxIdle:     1,
+		RateLimiter: &countLimiter{n: 2},
+		Dial: func() (redis.Conn, error) {
+			return redis.Dial("", "", dialTestConn("", io.Discard))
+		},
+	}
+	defer p.Close()
+
+	for i := 0; i < 2; i++ {
+		c := p.Get()
+		require.NoError(t, c.Err())
+		require.NoError(t, c.Close())
+	}
+
+	c, err := p.GetContext(context.Background())
+	require.ErrorIs(t, err, redis.ErrRateLimited)
+	require.EqualError(t, err, "redigo: rate limited: limit reached")
+	_, err = c.Do("PING")
+	require.ErrorIs(t, err, redis.ErrRateLimited)
+	require.Equal(t, 1, p.IdleCount())
+}
+
+// blockLimiter blocks until the context is done.
+type blockLimiter struct{}
+
+func (blockLimiter) Wait(ctx context.Context) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestPoolRateLimiterContext(t *testing.T) {
+	p := &redis.Pool{
+		RateLimiter: blockLimiter{},
+		Dial: func() (redis.Conn, error) {
+			t.Fatal("unexpected dial")
+			return nil, nil
+		},
+	}
+	defer p.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := p.GetContext(ctx)
+	require.Equal(t, context.Canceled, err)
+
+	ctx, cancel = context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	_, err = p.GetContext(ctx)
+	require.Equal(t, context.DeadlineExceeded, err)
+	require.False(t, errors.Is(err, redis.ErrRateLimited))
+}
+
 func TestPoolClose(t *testing.T) {
 	d := poolDialer{t: t}
 	p := &redis.Pool{
@@ -312,6 +623,355 @@ func TestPoolIdleTimeout(t *testing.T) {
 	d.check("2", p, 2, 1, 0)
 }
 
+// idleServerConn simulates a server that closes the connection after the
+// connection is idle for longer than timeout.
+type idleServerConn struct {
+	redis.Conn
+	timeout time.Duration
+
+	mu    sync.Mutex
+	last  time.Time
+	pings int
+	fail  bool
+}
+
+func (c *idleServerConn) Do(commandName string, args ...interface{}) (interface{}, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.fail || time.Since(c.last) > c.timeout {
+		c.fail = true
+		return nil, io.EOF
+	}
+	c.last = time.Now()
+	if commandName == "PING" {
+		c.pings++
+	}
+	return "OK", nil
+}
+
+func (c *idleServerConn) Err() error   { return nil }
+func (c *idleServerConn) Close() error { return nil }
+
+func (c *idleServerConn) pingCount() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.pings
+}
+
+func TestPoolIdleTimeoutAfterDialWait(t *testing.T) {
+	var mu sync.Mutex
+	now := time.Now()
+	redis.SetNowFunc(func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	})
+	defer redis.SetNowFunc(time.Now)
+
+	var conns []redis.Conn
+	dialStarted := make(chan struct{})
+	release := make(chan struct{})
+	p := &redis.Pool{
+		MaxIdle:            2,
+		IdleTimeout:        300 * time.Second,
+		MaxConcurrentDials: 1,
+		Dial: func() (redis.Conn, error) {
+			mu.Lock()
+			n := len(conns)
+			mu.Unlock()
+			if n == 1 {
+				close(dialStarted)
+				<-release
+			}
+			c, err := redis.Dial("", "", dialTestConn("", io.Discard))
+			mu.Lock()
+			conns = append(conns, c)
+			mu.Unlock()
+			return c, err
+		},
+	}
+	defer p.Close()
+
+	c1 := p.Get()
+	require.NoError(t, c1.Err())
+
+	// Hold the dial slot with a slow dial and wait for a dial slot in a
+	// second Get.
+	got := make(chan redis.Conn, 2)
+	go func() { got <- p.Get() }()
+	<-dialStarted
+	go func() { got <- p.Get() }()
+	time.Sleep(20 * time.Millisecond)
+
+	// The connection returned to the pool goes stale during the wait.
+	require.NoError(t, c1.Close())
+	mu.Lock()
+	now = now.Add(p.IdleTimeout + 1)
+	mu.Unlock()
+	close(release)
+
+	for i := 0; i < 2; i++ {
+		c := <-got
+		require.NoError(t, c.Err())
+		defer c.Close()
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, conns, 3)
+	require.Error(t, conns[0].Err(), "stale connection not closed")
+}
+
+func TestPoolIdleKeepAlive(t *testing.T) {
+	sc := &idleServerConn{timeout: 100 * time.Millisecond, last: time.Now()}
+	p := &redis.Pool{
+		MaxIdle:       1,
+		IdleKeepAlive: 40 * time.Millisecond,
+		Dial:          func() (redis.Conn, error) { return sc, nil },
+	}
+	defer p.Close()
+
+	c := p.Get()
+	_, err := c.Do("SET", "k", "v")
+	require.NoError(t, err)
+
+	// Borrowed connections are not pinged.
+	time.Sleep(60 * time.Millisecond)
+	require.Equal(t, 0, sc.pingCount())
+	_, err = c.Do("GET", "k")
+	require.NoError(t, err)
+	require.NoError(t, c.Close())
+
+	// The keepalive prevents the server timeout on the idle connection.
+	time.Sleep(300 * time.Millisecond)
+	require.NotZero(t, sc.pingCount())
+	c = p.Get()
+	_, err = c.Do("GET", "k")
+	require.NoError(t, err)
+	require.NoError(t, c.Close())
+	require.Equal(t, 1, p.ActiveCount())
+}
+
+func TestPoolIdleKeepAliveEvict(t *testing.T) {
+	sc := &idleServerConn{timeout: time.Hour, last: time.Now()}
+	p := &redis.Pool{
+		MaxIdle:       1,
+		IdleKeepAlive: 20 * time.Millisecond,
+		Dial:          func() (redis.Conn, error) { return sc, nil },
+	}
+	defer p.Close()
+
+	c := p.Get()
+	_, err := c.Do("PING")
+	require.NoError(t, err)
+	require.NoError(t, c.Close())
+	require.Equal(t, 1, p.IdleCount())
+
+	sc.mu.Lock()
+	sc.fail = true
+	sc.mu.Unlock()
+
+	// The connection that failed the keepalive PING is closed.
+	require.Eventually(t, func() bool {
+		return p.ActiveCount() == 0
+	}, time.Second, 5*time.Millisecond)
+	require.Equal(t, 0, p.IdleCount())
+}
+
+func TestPoolIdleKeepAliveEvictWait(t *testing.T) {
+	var mu sync.Mutex
+	var conns []*idleServerConn
+	p := &redis.Pool{
+		MaxIdle:       1,
+		MaxActive:     1,
+		Wait:          true,
+		IdleKeepAlive: 20 * time.Millisecond,
+		Dial: func() (redis.Conn, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			sc := &idleServerConn{timeout: time.Hour, last: time.Now()}
+			conns = append(conns, sc)
+			return sc, nil
+		},
+	}
+	defer p.Close()
+
+	c := p.Get()
+	_, err := c.Do("PING")
+	require.NoError(t, err)
+	require.NoError(t, c.Close())
+
+	mu.Lock()
+	conns[0].mu.Lock()
+	conns[0].fail = true
+	conns[0].mu.Unlock()
+	mu.Unlock()
+
+	// Evicting the connection must not return an extra token to the pool.
+	done := make(chan redis.PoolStats)
+	go func() {
+		for {
+			if stats := p.Stats(); stats.ActiveCount == 0 {
+				done <- stats
+				return
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}()
+	select {
+	case stats := <-done:
+		require.Equal(t, 0, stats.IdleCount)
+	case <-time.After(time.Second):
+		t.Fatal("pool did not evict the connection")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	c, err = p.GetContext(ctx)
+	require.NoError(t, err)
+	_, err = c.Do("PING")
+	require.NoError(t, err)
+	require.NoError(t, c.Close())
+	require.Equal(t, 1, p.ActiveCount())
+}
+
+func TestPoolIdleKeepAliveMinInterval(t *testing.T) {
+	sc := &idleServerConn{timeout: time.Hour, last: time.Now()}
+	p := &redis.Pool{
+		MaxIdle:       1,
+		IdleKeepAlive: time.Nanosecond,
+		Dial:          func() (redis.Conn, error) { return sc, nil },
+	}
+	defer p.Close()
+
+	c := p.Get()
+	require.NoError(t, c.Close())
+	require.Eventually(t, func() bool {
+		return sc.pingCount() > 0
+	}, time.Second, 5*time.Millisecond)
+}
+
+// blockPingConn is a connection that blocks PING until release is closed.
+type blockPingConn struct {
+	redis.Conn
+	pinging chan struct{}
+	release chan struct{}
+}
+
+func (c *blockPingConn) Do(commandName string, args ...interface{}) (interface{}, error) {
+	if commandName == "PING" {
+		select {
+		case c.pinging <- struct{}{}:
+		default:
+		}
+		<-c.release
+	}
+	return "OK", nil
+}
+
+func (c *blockPingConn) Err() error   { return nil }
+func (c *blockPingConn) Close() error { return nil }
+
+func TestPoolIdleKeepAliveMaxActive(t *testing.T) {
+	for _, wait := range []bool{false, true} {
+		t.Run(fmt.Sprintf("wait=%v", wait), func(t *testing.T) {
+			pinging := make(chan struct{}, 1)
+			release := make(chan struct{})
+			var dials int32
+			p := &redis.Pool{
+				MaxIdle:       2,
+				MaxActive:     2,
+				Wait:          wait,
+				IdleKeepAlive: 20 * time.Millisecond,
+				Dial: func() (redis.Conn, error) {
+					atomic.AddInt32(&dials, 1)
+					return &blockPingConn{pinging: pinging, release: release}, nil
+				},
+			}
+			defer p.Close()
+			defer func() {
+				select {
+				case <-release:
+				default:
+					close(release)
+				}
+			}()
+
+			// Hold MaxActive connections, one borrowed and one being pinged.
+			c1 := p.Get()
+			require.NoError(t, c1.Err())
+			defer c1.Close()
+			c2 := p.Get()
+			require.NoError(t, c2.Err())
+			require.NoError(t, c2.Close())
+			select {
+			case <-pinging:
+			case <-time.After(time.Second):
+				t.Fatal("keepalive did not ping the idle connection")
+			}
+
+			if !wait {
+				// The pinged connection does not exhaust the pool.
+				c3 := p.Get()
+				require.NoError(t, c3.Err())
+				require.NoError(t, c3.Close())
+				close(release)
+				require.Eventually(t, func() bool {
+					return p.ActiveCount() <= 2
+				}, time.Second, 5*time.Millisecond)
+				return
+			}
+
+			// Get waits for the pinged connection instead of dialing past
+			// MaxActive.
+			got := make(chan redis.Conn)
+			go func() { got <- p.Get() }()
+			select {
+			case c3 := <-got:
+				c3.Close()
+				t.Fatal("Get did not wait for the pinged connection")
+			case <-time.After(50 * time.Millisecond):
+			}
+			close(release)
+			c3 := <-got
+			require.NoError(t, c3.Err())
+			require.NoError(t, c3.Close())
+			require.Equal(t, int32(2), atomic.LoadInt32(&dials))
+			require.Equal(t, 2, p.ActiveCount())
+		})
+	}
+}
+
+func TestPoolIdleTimeoutOnBorrow(t *testing.T) {
+	now := time.Now()
+	redis.SetNowFunc(func() time.Time { return now })
+	defer redis.SetNowFunc(time.Now)
+
+	var conns []redis.Conn
+	p := &redis.Pool{
+		MaxIdle:     2,
+		IdleTimeout: 300 * time.Second,
+		Dial: func() (redis.Conn, error) {
+			c, err := redis.Dial("", "", dialTestConn("", io.Discard))
+			conns = append(conns, c)
+			return c, err
+		},
+	}
+	defer p.Close()
+
+	require.NoError(t, p.Get().Close())
+	require.Equal(t, 1, p.IdleCount())
+
+	now = now.Add(p.IdleTimeout + 1)
+
+	c := p.Get()
+	require.NoError(t, c.Err())
+	require.Len(t, conns, 2)
+	require.Error(t, conns[0].Err(), "idle connection not closed")
+	require.NoError(t, conns[1].Err())
+	require.Equal(t, 1, p.ActiveCount())
+	require.NoError(t, c.Close())
+}
+
 func TestPoolMaxLifetime(t *testing.T) {
 	d := poolDialer{t: t}
 	p := &redis.Pool{
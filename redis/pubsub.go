@@ -17,9 +17,15 @@ package redis
 import (
 	"context"
 	"errors"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// ErrPingTimeout is reported by the PubSubConn health check when the server
+// does not reply to a PING in time.
+var ErrPingTimeout = errors.New("redigo: pubsub PING timed out")
+
 // Subscription represents a subscribe or unsubscribe notification.
 type Subscription struct {
 	// Kind is "subscribe", "unsubscribe", "psubscribe" or "punsubscribe"
@@ -37,9 +43,16 @@ type Message struct {
 	// The originating channel.
 	Channel string
 
+	// The originating channel as received from the server. Unlike Channel,
+	// ChannelBytes preserves channel names that are not valid UTF-8.
+	ChannelBytes []byte
+
 	// The matched pattern, if any
 	Pattern string
 
+	// The matched pattern, if any, as received from the server.
+	PatternBytes []byte
+
 	// The message data.
 	Data []byte
 }
@@ -52,45 +65,80 @@ type Pong struct {
 // PubSubConn wraps a Conn with convenience methods for subscribers.
 type PubSubConn struct {
 	Conn Conn
+
+	count  *int64 // accessed atomically, see SubscriptionCount
+	health *pubSubHealth
+}
+
+// NewPubSubConn returns a PubSubConn for c that tracks the subscription
+// count reported by SubscriptionCount. Copies of the returned PubSubConn
+// share the count.
+func NewPubSubConn(c Conn) PubSubConn {
+	return PubSubConn{Conn: c, count: new(int64)}
+}
+
+// pubSubHealth is the state of the health check started by StartHealthCheck.
+type pubSubHealth struct {
+	// mu serializes the commands written by the health check with the
+	// commands written by the application.
+	mu   sync.Mutex
+	pong chan struct{}
+	done chan struct{}
+	once sync.Once
+
+	// exited is closed when the health check goroutine exits.
+	exited chan struct{}
+
+	// abort fails the blocked receive on a failed health check. Nil when
+	// the connection does not support aborting.
+	abort func(err error)
 }
 
-// Close closes the connection.
+func (h *pubSubHealth) stop() {
+	h.once.Do(func() { close(h.done) })
+}
+
+// Close stops the health check and closes the connection.
 func (c PubSubConn) Close() error {
+	if c.health != nil {
+		c.health.stop()
+		<-c.health.exited
+	}
 	return c.Conn.Close()
 }
 
-// Subscribe subscribes the connection to the specified channels.
-func (c PubSubConn) Subscribe(channel ...interface{}) error {
-	if err := c.Conn.Send("SUBSCRIBE", channel...); err != nil {
+// write sends and flushes a command.
+func (c PubSubConn) write(commandName string, args ...interface{}) error {
+	if c.health != nil {
+		c.health.mu.Lock()
+		defer c.health.mu.Unlock()
+	}
+	if err := c.Conn.Send(commandName, args...); err != nil {
 		return err
 	}
 	return c.Conn.Flush()
 }
 
+// Subscribe subscribes the connection to the specified channels.
+func (c PubSubConn) Subscribe(channel ...interface{}) error {
+	return c.write("SUBSCRIBE", channel...)
+}
+
 // PSubscribe subscribes the connection to the given patterns.
 func (c PubSubConn) PSubscribe(channel ...interface{}) error {
-	if err := c.Conn.Send("PSUBSCRIBE", channel...); err != nil {
-		return err
-	}
-	return c.Conn.Flush()
+	return c.write("PSUBSCRIBE", channel...)
 }
 
 // Unsubscribe unsubscribes the connection from the given channels, or from all
 // of them if none is given.
 func (c PubSubConn) Unsubscribe(channel ...interface{}) error {
-	if err := c.Conn.Send("UNSUBSCRIBE", channel...); err != nil {
-		return err
-	}
-	return c.Conn.Flush()
+	return c.write("UNSUBSCRIBE", channel...)
 }
 
 // PUnsubscribe unsubscribes the connection from the given patterns, or from all
 // of them if none is given.
 func (c PubSubConn) PUnsubscribe(channel ...interface{}) error {
-	if err := c.Conn.Send("PUNSUBSCRIBE", channel...); err != nil {
-		return err
-	}
-	return c.Conn.Flush()
+	return c.write("PUNSUBSCRIBE", channel...)
 }
 
 // Ping sends a PING to the server with the specified data.
@@ -98,10 +146,96 @@ func (c PubSubConn) PUnsubscribe(channel ...interface{}) error {
 // The connection must be subscribed to at least one channel or pattern when
 // calling this method.
 func (c PubSubConn) Ping(data string) error {
-	if err := c.Conn.Send("PING", data); err != nil {
-		return err
+	return c.write("PING", data)
+}
+
+// StartHealthCheck starts a goroutine that sends a PING to the server every
+// interval. If a Pong is not received within interval after a PING, then the
+// health check fails the connection, sends the failure to the returned
+// channel and closes the channel. The failure is ErrPingTimeout or the error
+// from writing the PING. A failed connection returns an error from the
+// blocked and subsequent calls to Receive. The application must close the
+// connection with Close as usual. The health check stops when the
+// connection is closed with Close.
+//
+// The health check cannot fail connections of types not created by this
+// package, such as wrappers. For those connections, the blocked Receive
+// returns when the connection's read timeout elapses.
+//
+// The Pong replies are returned from Receive as usual. The application must
+// receive continuously for the health check to see the replies. The
+// connection must be subscribed to at least one channel or pattern when the
+// health check starts. Call StartHealthCheck at most once, before receiving
+// and before copying the PubSubConn.
+func (c *PubSubConn) StartHealthCheck(interval time.Duration) <-chan error {
+	h := &pubSubHealth{
+		pong:   make(chan struct{}, 1),
+		done:   make(chan struct{}),
+		exited: make(chan struct{}),
+		abort:  connAbort(c.Conn),
+	}
+	c.health = h
+	errs := make(chan error, 1)
+	go c.healthCheck(h, interval, errs)
+	return errs
+}
+
+// connAbort returns the function that aborts c or nil if c does not support
+// aborting. The function for a pooled connection aborts the underlying
+// connection so that the function does not access the pooled connection
+// concurrently with the application.
+func connAbort(c Conn) func(err error) {
+	if ac, ok := c.(*activeConn); ok {
+		if ac.pc == nil {
+			return nil
+		}
+		c = ac.pc.c
+	}
+	if a, ok := c.(interface{ abort(err error) }); ok {
+		return a.abort
+	}
+	return nil
+}
+
+func (c PubSubConn) healthCheck(h *pubSubHealth, interval time.Duration, errs chan<- error) {
+	defer close(h.exited)
+	defer close(errs)
+	t := time.NewTimer(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-h.done:
+			return
+		case <-t.C:
+		}
+		// Discard a Pong received before the PING.
+		select {
+		case <-h.pong:
+		default:
+		}
+		err := c.write("PING")
+		if err == nil {
+			t.Reset(interval)
+			select {
+			case <-h.done:
+				return
+			case <-h.pong:
+				if !t.Stop() {
+					<-t.C
+				}
+				t.Reset(interval)
+				continue
+			case <-t.C:
+				err = ErrPingTimeout
+			}
+		}
+		h.stop()
+		if h.abort != nil {
+			h.abort(err)
+		}
+		errs <- err
+		return
 	}
-	return c.Conn.Flush()
 }
 
 // Receive returns a pushed message as a Subscription, Message, Pong or error.
@@ -124,6 +258,35 @@ func (c PubSubConn) ReceiveContext(ctx context.Context) interface{} {
 	return c.receiveInternal(ReceiveContext(c.Conn, ctx))
 }
 
+// Drain reads and discards the notifications that arrive until no
+// notification arrives within timeout and returns the number of
+// notifications discarded. Drain is a recovery aid for connections with
+// stale notifications, for example a connection reused after an error in a
+// subscriber. The discarded notifications are not reflected in
+// SubscriptionCount. Drain returns the number of notifications discarded
+// before an error if the connection fails or does not support draining.
+func (c PubSubConn) Drain(timeout time.Duration) int {
+	n, _ := DrainAll(c.Conn, timeout)
+	return n
+}
+
+// SubscriptionCount returns the number of channels and patterns that the
+// connection is subscribed to as reported by the most recent subscribe or
+// unsubscribe notification returned from Receive, ReceiveWithTimeout or
+// ReceiveContext. The count is zero when the connection is fully
+// unsubscribed. SubscriptionCount is safe to call concurrently with the
+// receive methods.
+//
+// The count is tracked only for a PubSubConn created with NewPubSubConn. A
+// PubSubConn created with a composite literal does not track the count and
+// SubscriptionCount returns zero.
+func (c PubSubConn) SubscriptionCount() int {
+	if c.count == nil {
+		return 0
+	}
+	return int(atomic.LoadInt64(c.count))
+}
+
 func (c PubSubConn) receiveInternal(replyArg interface{}, errArg error) interface{} {
 	reply, err := Values(replyArg, errArg)
 	if err != nil {
@@ -139,27 +302,39 @@ func (c PubSubConn) receiveInternal(replyArg interface{}, errArg error) interfac
 	switch kind {
 	case "message":
 		var m Message
-		if _, err := Scan(reply, &m.Channel, &m.Data); err != nil {
+		if _, err := Scan(reply, &m.ChannelBytes, &m.Data); err != nil {
 			return err
 		}
+		m.Channel = string(m.ChannelBytes)
 		return m
 	case "pmessage":
 		var m Message
-		if _, err := Scan(reply, &m.Pattern, &m.Channel, &m.Data); err != nil {
+		if _, err := Scan(reply, &m.PatternBytes, &m.ChannelBytes, &m.Data); err != nil {
 			return err
 		}
+		m.Pattern = string(m.PatternBytes)
+		m.Channel = string(m.ChannelBytes)
 		return m
 	case "subscribe", "psubscribe", "unsubscribe", "punsubscribe":
 		s := Subscription{Kind: kind}
 		if _, err := Scan(reply, &s.Channel, &s.Count); err != nil {
 			return err
 		}
+		if c.count != nil {
+			atomic.StoreInt64(c.count, int64(s.Count))
+		}
 		return s
 	case "pong":
 		var p Pong
 		if _, err := Scan(reply, &p.Data); err != nil {
 			return err
 		}
+		if c.health != nil {
+			select {
+			case c.health.pong <- struct{}{}:
+			default:
+			}
+		}
 		return p
 	}
 	return errors.New("redigo: unknown pubsub notification")
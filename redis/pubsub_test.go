@@ -17,6 +17,8 @@ package redis_test
 import (
 	"context"
 	"errors"
+	"io"
+	"net"
 	"reflect"
 	"testing"
 	"time"
@@ -61,7 +63,7 @@ func TestPushed(t *testing.T) {
 
 	_, err = pc.Do("PUBLISH", "c1", "hello")
 	require.NoError(t, err)
-	expectPushed(t, c, "PUBLISH c1 hello", redis.Message{Channel: "c1", Data: []byte("hello")})
+	expectPushed(t, c, "PUBLISH c1 hello", redis.Message{Channel: "c1", ChannelBytes: []byte("c1"), Data: []byte("hello")})
 
 	require.NoError(t, c.Ping("hello"))
 	expectPushed(t, c, `Ping("hello")`, redis.Pong{Data: "hello"})
@@ -98,3 +100,197 @@ func TestPubSubReceiveContext(t *testing.T) {
 		t.Errorf("recv w/canceled expected Canceled got %v", err)
 	}
 }
+
+func TestPubSubBinaryChannel(t *testing.T) {
+	r := "*3\r\n$7\r\nmessage\r\n$2\r\n\xff\x00\r\n$5\r\nhello\r\n" +
+		"*4\r\n$8\r\npmessage\r\n$2\r\n\xfe*\r\n$2\r\n\xfe\x01\r\n$5\r\nworld\r\n"
+	sc, err := redis.Dial("", "", dialTestConn(r, nil))
+	require.NoError(t, err)
+	c := redis.PubSubConn{Conn: sc}
+
+	expectPushed(t, c, "message", redis.Message{
+		Channel:      "\xff\x00",
+		ChannelBytes: []byte{0xff, 0x00},
+		Data:         []byte("hello"),
+	})
+	expectPushed(t, c, "pmessage", redis.Message{
+		Channel:      "\xfe\x01",
+		ChannelBytes: []byte{0xfe, 0x01},
+		Pattern:      "\xfe*",
+		PatternBytes: []byte{0xfe, '*'},
+		Data:         []byte("world"),
+	})
+}
+
+func TestPubSubSubscriptionCount(t *testing.T) {
+	r := "*3\r\n$9\r\nsubscribe\r\n$2\r\nc1\r\n:1\r\n" +
+		"*3\r\n$10\r\npsubscribe\r\n$2\r\np*\r\n:2\r\n" +
+		"*3\r\n$11\r\nunsubscribe\r\n$2\r\nc1\r\n:1\r\n" +
+		"*3\r\n$12\r\npunsubscribe\r\n$2\r\np*\r\n:0\r\n"
+	sc, err := redis.Dial("", "", dialTestConn(r, io.Discard))
+	require.NoError(t, err)
+	c := redis.NewPubSubConn(sc)
+	require.Equal(t, 0, c.SubscriptionCount())
+
+	for _, want := range []int{1, 2, 1, 0} {
+		_, ok := c.Receive().(redis.Subscription)
+		require.True(t, ok)
+		require.Equal(t, want, c.SubscriptionCount())
+	}
+
+	// A PubSubConn created with a composite literal does not track the count.
+	sc, err = redis.Dial("", "", dialTestConn(r, io.Discard))
+	require.NoError(t, err)
+	lc := redis.PubSubConn{Conn: sc}
+	_, ok := lc.Receive().(redis.Subscription)
+	require.True(t, ok)
+	require.Equal(t, 0, lc.SubscriptionCount())
+}
+
+func TestNewPubSubConnSubscriptionCount(t *testing.T) {
+	r := "*3\r\n$9\r\nsubscribe\r\n$2\r\nc1\r\n:1\r\n" +
+		"*3\r\n$9\r\nsubscribe\r\n$2\r\nc2\r\n:2\r\n"
+	sc, err := redis.Dial("", "", dialTestConn(r, io.Discard))
+	require.NoError(t, err)
+	c := redis.NewPubSubConn(sc)
+
+	// Receive has a value receiver. The copy shares the count.
+	var receiver interface{ Receive() interface{} } = c
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 2; i++ {
+			_, ok := receiver.Receive().(redis.Subscription)
+			require.True(t, ok)
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		c.SubscriptionCount()
+	}
+	<-done
+	require.Equal(t, 2, c.SubscriptionCount())
+}
+
+// servePubSub replies to SUBSCRIBE and to the first pings PING commands on
+// the server side of a pipe. Later PING commands are read and ignored.
+func servePubSub(server net.Conn, pings int) {
+	sc := redis.NewConn(server, 0, 0)
+	defer server.Close()
+	for {
+		cmd, err := redis.Strings(sc.Receive())
+		if err != nil {
+			return
+		}
+		var reply string
+		switch cmd[0] {
+		case "SUBSCRIBE":
+			reply = "*3\r\n$9\r\nsubscribe\r\n$1\r\nc\r\n:1\r\n"
+		case "PING":
+			if pings == 0 {
+				continue
+			}
+			pings--
+			reply = "*2\r\n$4\r\npong\r\n$0\r\n\r\n"
+		}
+		if _, err := server.Write([]byte(reply)); err != nil {
+			return
+		}
+	}
+}
+
+func TestPubSubHealthCheck(t *testing.T) {
+	client, server := net.Pipe()
+	go servePubSub(server, 2)
+	c := redis.PubSubConn{Conn: redis.NewConn(client, 0, 0)}
+	require.NoError(t, c.Subscribe("c"))
+	_, ok := c.Receive().(redis.Subscription)
+	require.True(t, ok)
+
+	errs := c.StartHealthCheck(20 * time.Millisecond)
+	pongs := 0
+	for {
+		v := c.Receive()
+		if _, ok := v.(redis.Pong); ok {
+			pongs++
+			continue
+		}
+		_, ok := v.(error)
+		require.True(t, ok, "Receive returned %v, want error", v)
+		break
+	}
+	require.Equal(t, 2, pongs)
+	require.Equal(t, redis.ErrPingTimeout, <-errs)
+	_, open := <-errs
+	require.False(t, open)
+}
+
+func TestPubSubHealthCheckPool(t *testing.T) {
+	p := &redis.Pool{
+		MaxIdle: 1,
+		Dial: func() (redis.Conn, error) {
+			client, server := net.Pipe()
+			go servePubSub(server, 1)
+			return redis.NewConn(client, 0, 0), nil
+		},
+	}
+	defer p.Close()
+
+	c := redis.PubSubConn{Conn: p.Get()}
+	require.NoError(t, c.Subscribe("c"))
+	_, ok := c.Receive().(redis.Subscription)
+	require.True(t, ok)
+
+	errs := c.StartHealthCheck(20 * time.Millisecond)
+	_, ok = c.Receive().(redis.Pong)
+	require.True(t, ok)
+	err, ok := c.Receive().(error)
+	require.True(t, ok)
+	require.Equal(t, redis.ErrPingTimeout, <-errs)
+	require.Error(t, err)
+
+	// The application closes the failed connection and the pool discards it.
+	c.Close() // nolint: errcheck
+	require.Equal(t, 0, p.ActiveCount())
+}
+
+func TestPubSubHealthCheckClose(t *testing.T) {
+	client, server := net.Pipe()
+	go servePubSub(server, -1)
+	c := redis.PubSubConn{Conn: redis.NewConn(client, 0, 0)}
+	require.NoError(t, c.Subscribe("c"))
+	_, ok := c.Receive().(redis.Subscription)
+	require.True(t, ok)
+
+	errs := c.StartHealthCheck(time.Millisecond)
+	for i := 0; i < 5; i++ {
+		_, ok := c.Receive().(redis.Pong)
+		require.True(t, ok)
+	}
+	require.NoError(t, c.Close())
+	_, open := <-errs
+	require.False(t, open)
+}
+
+func TestPubSubDrain(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+	go func() {
+		server.Write([]byte("*3\r\n$7\r\nmessage\r\n$1\r\nc\r\n$1\r\na\r\n" + // nolint: errcheck
+			"*3\r\n$7\r\nmessage\r\n$1\r\nc\r\n$1\r\nb\r\n" +
+			"*3\r\n$11\r\nunsubscribe\r\n$1\r\nc\r\n:0\r\n"))
+		sc := redis.NewConn(server, 0, 0)
+		if _, err := sc.Receive(); err != nil {
+			return
+		}
+		server.Write([]byte("*3\r\n$9\r\nsubscribe\r\n$1\r\nd\r\n:1\r\n")) // nolint: errcheck
+	}()
+
+	c := redis.PubSubConn{Conn: redis.NewConn(client, 0, 0)}
+	require.Equal(t, 3, c.Drain(50*time.Millisecond))
+
+	// The connection is usable after draining.
+	require.NoError(t, c.Subscribe("d"))
+	s, ok := c.Receive().(redis.Subscription)
+	require.True(t, ok)
+	require.Equal(t, "d", s.Channel)
+}
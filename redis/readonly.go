@@ -0,0 +1,142 @@
+// Copyright 2012 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	_ ConnWithTimeout = (*readOnlyConn)(nil)
+	_ ConnWithContext = (*readOnlyConn)(nil)
+	_ ConnWithKey     = (*readOnlyConn)(nil)
+)
+
+// ErrWriteCommand is returned by a connection created with NewReadOnlyConn
+// when the application attempts to send a command that is not read-only.
+var ErrWriteCommand = errors.New("redigo: write command on read-only connection")
+
+var (
+	readOnlyCommands = map[string]bool{}
+
+	// dataReadCommands is the subset of readOnlyCommands that read data.
+	// The connection, transaction and server commands in readOnlyCommands
+	// change or report connection state and are not in the subset.
+	dataReadCommands = map[string]bool{}
+)
+
+func init() {
+	for _, n := range strings.Fields(`
+		AUTH CLIENT COMMAND DISCARD ECHO EXEC HELLO INFO MULTI PING QUIT
+		READONLY RESET SELECT TIME UNWATCH WATCH
+		PSUBSCRIBE PUNSUBSCRIBE SSUBSCRIBE SUNSUBSCRIBE SUBSCRIBE UNSUBSCRIBE`) {
+		readOnlyCommands[n] = true
+	}
+	for _, n := range strings.Fields(`
+		BITCOUNT BITFIELD_RO BITPOS DBSIZE DUMP EVAL_RO EVALSHA_RO EXISTS
+		EXPIRETIME FCALL_RO GEODIST GEOHASH GEOPOS GEORADIUS_RO
+		GEORADIUSBYMEMBER_RO GEOSEARCH GET GETBIT GETRANGE HEXISTS HGET
+		HGETALL HKEYS HLEN HMGET HRANDFIELD HSCAN HSTRLEN HVALS KEYS LCS
+		LINDEX LLEN LPOS LRANGE MGET OBJECT PEXPIRETIME PFCOUNT PTTL
+		RANDOMKEY SCAN SCARD SDIFF SINTER SINTERCARD SISMEMBER SMEMBERS
+		SMISMEMBER SORT_RO SRANDMEMBER SSCAN STRLEN SUBSTR SUNION TOUCH TTL
+		TYPE XINFO XLEN XPENDING XRANGE XREAD XREVRANGE ZCARD ZCOUNT ZDIFF
+		ZINTER ZINTERCARD ZLEXCOUNT ZMSCORE ZRANDMEMBER ZRANGE ZRANGEBYLEX
+		ZRANGEBYSCORE ZRANK ZREVRANGE ZREVRANGEBYLEX ZREVRANGEBYSCORE
+		ZREVRANK ZSCAN ZSCORE ZUNION`) {
+		readOnlyCommands[n] = true
+		dataReadCommands[n] = true
+	}
+}
+
+// IsReadOnlyCommand reports whether the command does not modify data on the
+// server according to a built-in table of commands. Connection and
+// transaction commands such as PING, SELECT, MULTI and EXEC are considered
+// read-only.
+func IsReadOnlyCommand(commandName string) bool {
+	return readOnlyCommands[strings.ToUpper(commandName)]
+}
+
+// NewReadOnlyConn returns a wrapper around a connection that returns
+// ErrWriteCommand for commands that are not read-only instead of sending the
+// commands to the server. The isReadOnly function reports whether a command
+// is read-only. If isReadOnly is nil, then IsReadOnlyCommand is used.
+// Applications using module or other custom commands can supply a function
+// that checks those commands and falls back to IsReadOnlyCommand.
+func NewReadOnlyConn(conn Conn, isReadOnly func(commandName string) bool) Conn {
+	if isReadOnly == nil {
+		isReadOnly = IsReadOnlyCommand
+	}
+	return &readOnlyConn{conn, isReadOnly}
+}
+
+type readOnlyConn struct {
+	Conn
+	isReadOnly func(commandName string) bool
+}
+
+func (c *readOnlyConn) check(commandName string) error {
+	if commandName == "" || c.isReadOnly(commandName) {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrWriteCommand, commandName)
+}
+
+func (c *readOnlyConn) Do(commandName string, args ...interface{}) (interface{}, error) {
+	if err := c.check(commandName); err != nil {
+		return nil, err
+	}
+	return c.Conn.Do(commandName, args...)
+}
+
+func (c *readOnlyConn) DoContext(ctx context.Context, commandName string, args ...interface{}) (interface{}, error) {
+	if err := c.check(commandName); err != nil {
+		return nil, err
+	}
+	return DoContext(c.Conn, ctx, commandName, args...)
+}
+
+func (c *readOnlyConn) DoWithTimeout(timeout time.Duration, commandName string, args ...interface{}) (interface{}, error) {
+	if err := c.check(commandName); err != nil {
+		return nil, err
+	}
+	return DoWithTimeout(c.Conn, timeout, commandName, args...)
+}
+
+func (c *readOnlyConn) DoWithKey(routingKey string, commandName string, args ...interface{}) (interface{}, error) {
+	if err := c.check(commandName); err != nil {
+		return nil, err
+	}
+	return DoWithKey(c.Conn, routingKey, commandName, args...)
+}
+
+func (c *readOnlyConn) Send(commandName string, args ...interface{}) error {
+	if err := c.check(commandName); err != nil {
+		return err
+	}
+	return c.Conn.Send(commandName, args...)
+}
+
+func (c *readOnlyConn) ReceiveContext(ctx context.Context) (interface{}, error) {
+	return ReceiveContext(c.Conn, ctx)
+}
+
+func (c *readOnlyConn) ReceiveWithTimeout(timeout time.Duration) (interface{}, error) {
+	return ReceiveWithTimeout(c.Conn, timeout)
+}
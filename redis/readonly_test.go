@@ -0,0 +1,45 @@
+// Copyright 2012 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package redis_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadOnlyConn(t *testing.T) {
+	var buf bytes.Buffer
+	c, err := redis.Dial("", "", dialTestConn("$1\r\nv\r\n+OK\r\n", &buf))
+	require.NoError(t, err)
+
+	ro := redis.NewReadOnlyConn(c, nil)
+	v, err := redis.String(ro.Do("get", "k"))
+	require.NoError(t, err)
+	require.Equal(t, "v", v)
+
+	_, err = ro.Do("SET", "k", "v")
+	require.ErrorIs(t, err, redis.ErrWriteCommand)
+	require.ErrorIs(t, ro.Send("DEL", "k"), redis.ErrWriteCommand)
+	require.Equal(t, "*2\r\n$3\r\nget\r\n$1\r\nk\r\n", buf.String())
+
+	ro = redis.NewReadOnlyConn(c, func(commandName string) bool {
+		return commandName == "JSON.GET" || redis.IsReadOnlyCommand(commandName)
+	})
+	_, err = ro.Do("JSON.GET", "k")
+	require.NoError(t, err)
+}
@@ -0,0 +1,58 @@
+// Copyright 2012 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package redis
+
+import (
+	"errors"
+	"strings"
+)
+
+// ReadWritePool is a pair of pools for a master and its replicas.
+type ReadWritePool struct {
+	// Master is the pool for the master.
+	Master *Pool
+
+	// Replica is the pool for the replicas.
+	Replica *Pool
+}
+
+// DoAndRetryReadOnly executes a command on a connection from the master
+// pool. If the command reads data and fails with an error other than an
+// error reply from the server, then the command is executed again on a
+// connection from the replica pool. Error replies are returned without a
+// retry. Connection, transaction and server commands such as AUTH, SELECT,
+// MULTI, EXEC, CLIENT and SUBSCRIBE are not retried even though
+// IsReadOnlyCommand reports them as read-only, because they act on the
+// state of a single connection.
+//
+// The reply from a replica can be stale. Use DoAndRetryReadOnly only for
+// reads where availability matters more than consistency.
+func (p *ReadWritePool) DoAndRetryReadOnly(commandName string, args ...interface{}) (interface{}, error) {
+	reply, err := p.do(p.Master, commandName, args)
+	if err == nil || p.Replica == nil || !dataReadCommands[strings.ToUpper(commandName)] {
+		return reply, err
+	}
+	var e Error
+	if errors.As(err, &e) {
+		return reply, err
+	}
+	return p.do(p.Replica, commandName, args)
+}
+
+func (p *ReadWritePool) do(pool *Pool, commandName string, args []interface{}) (interface{}, error) {
+	c := pool.Get()
+	defer c.Close()
+	return c.Do(commandName, args...)
+}
@@ -0,0 +1,74 @@
+// Copyright 2012 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package redis_test
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDoAndRetryReadOnly(t *testing.T) {
+	errMaster := errors.New("master down")
+	replicaDials := 0
+	p := &redis.ReadWritePool{
+		Master: &redis.Pool{
+			Dial: func() (redis.Conn, error) { return nil, errMaster },
+		},
+		Replica: &redis.Pool{
+			Dial: func() (redis.Conn, error) {
+				replicaDials++
+				return redis.Dial("", "", dialTestConn("$1\r\nv\r\n", io.Discard))
+			},
+		},
+	}
+	defer p.Master.Close()
+	defer p.Replica.Close()
+
+	v, err := redis.String(p.DoAndRetryReadOnly("GET", "k"))
+	require.NoError(t, err)
+	require.Equal(t, "v", v)
+	require.Equal(t, 1, replicaDials)
+
+	for _, cmd := range []string{"SET", "SELECT", "MULTI", "EXEC", "AUTH", "CLIENT", "SUBSCRIBE"} {
+		_, err = p.DoAndRetryReadOnly(cmd, "k")
+		require.ErrorIs(t, err, errMaster, cmd)
+	}
+	require.Equal(t, 1, replicaDials)
+}
+
+func TestDoAndRetryReadOnlyErrorReply(t *testing.T) {
+	p := &redis.ReadWritePool{
+		Master: &redis.Pool{
+			Dial: func() (redis.Conn, error) {
+				return redis.Dial("", "", dialTestConn("-WRONGTYPE x\r\n", io.Discard))
+			},
+		},
+		Replica: &redis.Pool{
+			Dial: func() (redis.Conn, error) {
+				t.Fatal("unexpected replica dial")
+				return nil, nil
+			},
+		},
+	}
+	defer p.Master.Close()
+	defer p.Replica.Close()
+
+	_, err := p.DoAndRetryReadOnly("GET", "k")
+	require.EqualError(t, err, "WRONGTYPE x")
+}
@@ -17,6 +17,7 @@ package redis
 import (
 	"context"
 	"errors"
+	"strconv"
 	"time"
 )
 
@@ -114,6 +115,25 @@ type ConnWithContext interface {
 	ReceiveContext(ctx context.Context) (reply interface{}, err error)
 }
 
+// ConnWithKey is an optional interface that allows the caller to pass the
+// logical key of a command to connection wrappers. The routing key is not
+// sent to the server. Wrappers such as proxies and key namespacing layers use
+// the routing key for commands where the key is not the first argument, for
+// example EVAL and GEORADIUS with STORE.
+//
+// All of the Conn implementations in this package satisfy the ConnWithKey
+// interface. Without a wrapper that uses the routing key, DoWithKey behaves
+// exactly like Do.
+//
+// Use the DoWithKey helper function to simplify use of this interface.
+type ConnWithKey interface {
+	Conn
+
+	// DoWithKey sends a command to the server and returns the received reply.
+	// The routingKey is available to wrappers and is not sent to the server.
+	DoWithKey(routingKey string, commandName string, args ...interface{}) (reply interface{}, err error)
+}
+
 var errTimeoutNotSupported = errors.New("redis: connection does not support ConnWithTimeout")
 var errContextNotSupported = errors.New("redis: connection does not support ConnWithContext")
 
@@ -131,6 +151,106 @@ func DoContext(c Conn, ctx context.Context, cmd string, args ...interface{}) (in
 	return cwt.DoContext(ctx, cmd, args...)
 }
 
+// DoWithKey executes a Redis command with the specified routing key. If the
+// connection does not satisfy the ConnWithKey interface, then the command is
+// executed with Do and the routing key is ignored.
+func DoWithKey(c Conn, routingKey string, cmd string, args ...interface{}) (interface{}, error) {
+	cwk, ok := c.(ConnWithKey)
+	if !ok {
+		return c.Do(cmd, args...)
+	}
+	return cwk.DoWithKey(routingKey, cmd, args...)
+}
+
+// ClientID returns the server assigned ID of the connection. The connections
+// in this package cache the ID after the first call. For other connections,
+// ClientID issues the CLIENT ID command on every call.
+func ClientID(c Conn) (int64, error) {
+	if cid, ok := c.(interface{ ClientID() (int64, error) }); ok {
+		return cid.ClientID()
+	}
+	return Int64(c.Do("CLIENT", "ID"))
+}
+
+// DoRaw sends the pre-encoded command resp to the server and returns the
+// received reply. The caller is responsible for resp being valid RESP that
+// produces exactly one reply. Proxies and replay tools use DoRaw to forward
+// commands without decoding them. If the connection does not support DoRaw,
+// then an error is returned.
+func DoRaw(c Conn, resp []byte) (interface{}, error) {
+	cr, ok := c.(interface {
+		DoRaw(resp []byte) (interface{}, error)
+	})
+	if !ok {
+		return nil, errors.New("redigo: connection does not support DoRaw")
+	}
+	return cr.DoRaw(resp)
+}
+
+// DoEach sends a command to the server and calls fn with each element of
+// the array reply. Connections created by this package stream the elements
+// to fn as they are read, so the size of the reply does not determine the
+// memory used. The value passed to fn is valid only until fn returns. If fn
+// returns an error, then DoEach returns the error. If the connection does
+// not support DoEach, then DoEach reads the whole reply with Do.
+func DoEach(c Conn, fn func(index int, value []byte) error, commandName string, args ...interface{}) error {
+	if ce, ok := c.(interface {
+		DoEach(fn func(index int, value []byte) error, commandName string, args ...interface{}) error
+	}); ok {
+		return ce.DoEach(fn, commandName, args...)
+	}
+	values, err := ByteSlices(c.Do(commandName, args...))
+	if err != nil {
+		return err
+	}
+	for i, v := range values {
+		if err := fn(i, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Drain reads and discards n replies to resynchronize a connection where
+// the application lost track of the number of pending replies, for example
+// after a panic in the middle of a pipeline. Drain is a last resort. Closing
+// the connection is usually the better choice. If the connection does not
+// support Drain, then an error is returned.
+func Drain(c Conn, n int) error {
+	cd, ok := c.(interface{ Drain(n int) error })
+	if !ok {
+		return errors.New("redigo: connection does not support Drain")
+	}
+	return cd.Drain(n)
+}
+
+// DrainAll reads and discards replies until no reply arrives within timeout
+// and returns the number of replies discarded. Like Drain, DrainAll is a last
+// resort. If the connection does not support DrainAll, then an error is
+// returned.
+func DrainAll(c Conn, timeout time.Duration) (int, error) {
+	cd, ok := c.(interface {
+		DrainAll(timeout time.Duration) (int, error)
+	})
+	if !ok {
+		return 0, errors.New("redigo: connection does not support DrainAll")
+	}
+	return cd.DrainAll(timeout)
+}
+
+// SetCommandDeadline sets the deadline for the next command issued on the
+// connection with Do. The deadline is cleared when the command completes.
+// SetCommandDeadline is a lower level alternative to DoContext for
+// applications that manage their own deadlines. If the connection does not
+// support SetCommandDeadline, then an error is returned.
+func SetCommandDeadline(c Conn, t time.Time) error {
+	cd, ok := c.(interface{ SetCommandDeadline(t time.Time) error })
+	if !ok {
+		return errors.New("redigo: connection does not support SetCommandDeadline")
+	}
+	return cd.SetCommandDeadline(t)
+}
+
 // DoWithTimeout executes a Redis command with the specified read timeout. If
 // the connection does not satisfy the ConnWithTimeout interface, then an error
 // is returned.
@@ -167,6 +287,12 @@ func ReceiveWithTimeout(c Conn, timeout time.Duration) (interface{}, error) {
 	return cwt.ReceiveWithTimeout(timeout)
 }
 
+// PipeCommand represents a command name and its arguments.
+type PipeCommand struct {
+	Name string
+	Args []interface{}
+}
+
 // SlowLog represents a redis SlowLog
 type SlowLog struct {
 	// ID is a unique progressive identifier for every slow log entry.
@@ -187,3 +313,198 @@ type SlowLog struct {
 	// ClientName is the name set via the CLIENT SETNAME command (4.0 only).
 	ClientName string
 }
+
+// XMessage represents a stream entry.
+type XMessage struct {
+	// ID is the entry ID.
+	ID string
+
+	// Fields is the entry's field-value pairs. Fields is nil for entries that
+	// were deleted from the stream but are still referenced by a consumer
+	// group's pending entries list.
+	Fields map[string]string
+}
+
+// ReplicationStatus represents the replication section of the INFO command.
+type ReplicationStatus struct {
+	// Role is "master" or "slave".
+	Role string
+
+	// ConnectedSlaves is the number of connected replicas.
+	ConnectedSlaves int
+
+	// MasterReplOffset is the replication offset of the server.
+	MasterReplOffset int64
+
+	// Slaves is the connected replicas.
+	Slaves []SlaveInfo
+}
+
+// SlaveInfo represents a replica in the replication section of the INFO
+// command.
+type SlaveInfo struct {
+	IP     string
+	Port   int
+	State  string
+	Offset int64
+	Lag    int64
+}
+
+// ClusterNode represents a line in the reply of the CLUSTER NODES command.
+type ClusterNode struct {
+	// ID is the node ID.
+	ID string
+
+	// Addr is the client address of the node as host:port. The cluster bus
+	// port and hostname are not included.
+	Addr string
+
+	// Flags is the node flags such as myself, master, slave and fail.
+	Flags []string
+
+	// Master is the ID of the master when the node is a replica and "" when
+	// the node is a master.
+	Master string
+
+	// PingSent and PongRecv are the unix times in milliseconds when the
+	// last ping was sent and the last pong was received.
+	PingSent, PongRecv int64
+
+	// ConfigEpoch is the configuration epoch of the node.
+	ConfigEpoch int64
+
+	// LinkState is "connected" or "disconnected".
+	LinkState string
+
+	// Slots is the inclusive ranges of hash slots served by the node.
+	// Slots being imported or migrated are not included.
+	Slots [][2]int
+}
+
+// ZMember represents a member of a sorted set and its score.
+type ZMember struct {
+	Member string
+	Score  float64
+}
+
+// XConsumer represents a consumer in the reply of the XINFO CONSUMERS
+// command.
+type XConsumer struct {
+	// Name is the name of the consumer.
+	Name string
+
+	// Pending is the number of messages delivered to the consumer and not
+	// acknowledged.
+	Pending int64
+
+	// Idle is the time since the consumer last attempted an interaction.
+	Idle time.Duration
+
+	// Inactive is the time since the consumer last successfully interacted.
+	// Inactive is -1 when the consumer never interacted successfully and is
+	// zero for servers before Redis 7.2 that do not report the field.
+	Inactive time.Duration
+}
+
+// ACLUserInfo represents a user returned by the ACL GETUSER command.
+type ACLUserInfo struct {
+	// Flags is the user's flags, for example "on" and "nopass".
+	Flags []string
+
+	// Passwords is the SHA-256 hashes of the user's passwords.
+	Passwords []string
+
+	// Commands is the user's command rules, for example "+@all -debug".
+	Commands string
+
+	// Keys is the user's key patterns. Servers before Redis 7.0 reply
+	// with an array of patterns, later servers reply with a space separated
+	// string. Both forms are returned as a slice of patterns.
+	Keys []string
+
+	// Channels is the user's pub/sub channel patterns in the same form as Keys
+	// (6.2 and later).
+	Channels []string
+
+	// Selectors is the user's selectors (7.0 and later).
+	Selectors []map[string]string
+
+	// Extra holds the values of fields not recognized by this package.
+	Extra map[string]interface{}
+}
+
+// LCSMatch represents a match returned by the LCS command with the IDX option.
+type LCSMatch struct {
+	// A and B are the inclusive start and end positions of the match in the
+	// first and second strings.
+	A, B [2]int64
+
+	// Len is the length of the match. Len is set only when the LCS command
+	// is called with the WITHMATCHLEN option.
+	Len int64
+}
+
+// LCSResult represents the reply of the LCS command with the IDX option.
+type LCSResult struct {
+	// MatchLen is the length of the longest common subsequence.
+	MatchLen int64
+
+	// Matches is the matched ranges.
+	Matches []LCSMatch
+}
+
+// DebugObjectInfo represents the reply of the DEBUG OBJECT command.
+type DebugObjectInfo struct {
+	// RefCount is the reference count of the value.
+	RefCount int64
+
+	// Encoding is the internal encoding of the value.
+	Encoding string
+
+	// SerializedLength is the length of the value when serialized in RDB
+	// format.
+	SerializedLength int64
+
+	// LRU is the LRU clock of the value.
+	LRU int64
+
+	// LRUSecondsIdle is the number of seconds since the value was accessed.
+	LRUSecondsIdle int64
+
+	// Extra contains the fields not parsed above, such as the value address
+	// reported as "at" and the quicklist fields of lists.
+	Extra map[string]string
+}
+
+// RedisType represents the type of value stored at a key as reported by the
+// TYPE command.
+type RedisType int
+
+// Types returned by the TYPE command.
+const (
+	RedisTypeNone RedisType = iota
+	RedisTypeString
+	RedisTypeList
+	RedisTypeSet
+	RedisTypeZSet
+	RedisTypeHash
+	RedisTypeStream
+)
+
+var redisTypeNames = [...]string{
+	RedisTypeNone:   "none",
+	RedisTypeString: "string",
+	RedisTypeList:   "list",
+	RedisTypeSet:    "set",
+	RedisTypeZSet:   "zset",
+	RedisTypeHash:   "hash",
+	RedisTypeStream: "stream",
+}
+
+// String returns the name of the type as used by the TYPE command.
+func (t RedisType) String() string {
+	if t < 0 || int(t) >= len(redisTypeNames) {
+		return "RedisType(" + strconv.Itoa(int(t)) + ")"
+	}
+	return redisTypeNames[t]
+}
@@ -15,10 +15,15 @@
 package redis
 
 import (
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
 	"strconv"
+	"strings"
 	"time"
+	"unicode/utf8"
 )
 
 // ErrNil indicates that a reply value is nil.
@@ -82,6 +87,32 @@ func Int64(reply interface{}, err error) (int64, error) {
 	return 0, fmt.Errorf("redigo: unexpected type for Int64, got type %T", reply)
 }
 
+// StoreCount is a helper that converts the reply of a command that stores
+// its result in a destination key, such as ZRANGESTORE, SORT with STORE,
+// SINTERSTORE and GEOSEARCHSTORE, to the number of elements stored. A reply
+// of 0 is valid and indicates that the destination key was deleted or not
+// created. If err is not equal to nil, then StoreCount returns 0, err.
+// Otherwise, StoreCount converts the reply as follows:
+//
+//  Reply type    Result
+//  integer       reply, nil
+//  nil           0, ErrNil
+//  other         0, error
+func StoreCount(reply interface{}, err error) (int64, error) {
+	if err != nil {
+		return 0, err
+	}
+	switch reply := reply.(type) {
+	case int64:
+		return reply, nil
+	case nil:
+		return 0, ErrNil
+	case Error:
+		return 0, reply
+	}
+	return 0, fmt.Errorf("redigo: unexpected type for StoreCount, got type %T", reply)
+}
+
 func errNegativeInt(v int64) error {
 	return fmt.Errorf("redigo: unexpected negative value %v for Uint64", v)
 }
@@ -140,6 +171,160 @@ func Float64(reply interface{}, err error) (float64, error) {
 	return 0, fmt.Errorf("redigo: unexpected type for Float64, got type %T", reply)
 }
 
+// Float32 is a helper that converts a command reply to a 32 bit float. If
+// err is not equal to nil, then Float32 returns 0, err. Otherwise, Float32
+// converts the reply to a float32 as follows:
+//
+//  Reply type    Result
+//  bulk string   parsed reply, nil
+//  nil           0, ErrNil
+//  other         0, error
+//
+// If the value overflows a float32, then Float32 returns an error wrapping
+// strconv.ErrRange.
+func Float32(reply interface{}, err error) (float32, error) {
+	if err != nil {
+		return 0, err
+	}
+	switch reply := reply.(type) {
+	case []byte:
+		n, err := strconv.ParseFloat(string(reply), 32)
+		return float32(n), err
+	case nil:
+		return 0, ErrNil
+	case Error:
+		return 0, reply
+	}
+	return 0, fmt.Errorf("redigo: unexpected type for Float32, got type %T", reply)
+}
+
+// Duration is a helper that converts a numeric command reply in multiples of
+// unit to a time.Duration. Use unit time.Second for commands such as TTL and
+// OBJECT IDLETIME and time.Millisecond for commands such as PTTL. If err is
+// not equal to nil, then Duration returns 0, err. Otherwise, Duration
+// converts the reply as follows:
+//
+//  Reply type    Result
+//  integer       time.Duration(reply) * unit, nil
+//  bulk string   parsed reply * unit, nil
+//  nil           0, ErrNil
+//  other         0, error
+//
+// A bulk string is parsed as an integer or as a decimal number such as
+// "1.5", which is rounded to the nearest nanosecond. Negative values are
+// returned as is, without multiplying by unit, so that callers can check the
+// -1 and -2 replies of TTL and PTTL for a key without an expiration and a
+// missing key.
+func Duration(reply interface{}, err error, unit time.Duration) (time.Duration, error) {
+	if err != nil {
+		return 0, err
+	}
+	var n int64
+	switch reply := reply.(type) {
+	case int64:
+		n = reply
+	case []byte:
+		n, err = strconv.ParseInt(string(reply), 10, 64)
+		if err != nil {
+			f, ferr := strconv.ParseFloat(string(reply), 64)
+			if ferr != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+				return 0, err
+			}
+			return floatDuration(f, unit)
+		}
+	case nil:
+		return 0, ErrNil
+	case Error:
+		return 0, reply
+	default:
+		return 0, fmt.Errorf("redigo: unexpected type for Duration, got type %T", reply)
+	}
+	if n < 0 {
+		return time.Duration(n), nil
+	}
+	if unit <= 0 {
+		return 0, fmt.Errorf("redigo: Duration requires a positive unit, got %v", unit)
+	}
+	if n > math.MaxInt64/int64(unit) {
+		return 0, fmt.Errorf("redigo: Duration value %d overflows time.Duration", n)
+	}
+	return time.Duration(n) * unit, nil
+}
+
+// floatDuration converts the decimal value f in multiples of unit to a
+// time.Duration using the rules of Duration.
+func floatDuration(f float64, unit time.Duration) (time.Duration, error) {
+	if f < 0 {
+		return time.Duration(math.Round(f)), nil
+	}
+	if unit <= 0 {
+		return 0, fmt.Errorf("redigo: Duration requires a positive unit, got %v", unit)
+	}
+	d := math.Round(f * float64(unit))
+	if d >= math.MaxInt64 {
+		return 0, fmt.Errorf("redigo: Duration value %v overflows time.Duration", f)
+	}
+	return time.Duration(d), nil
+}
+
+// Scalar is a helper that converts a command reply to the most specific
+// scalar type that represents the reply. Scalar is a best-effort conversion
+// for displaying values of unknown type in tools such as a REPL or CLI.
+// Application code should use the typed helpers such as Int64 and Bytes.
+// If err is not equal to nil, then Scalar returns nil, err. Otherwise,
+// Scalar converts the reply as follows:
+//
+//  Reply type      Result
+//  integer         reply, nil
+//  bulk string     int64 if the value parses as a base 10 integer, nil
+//                  float64 if the value parses as a decimal float, nil
+//                  reply, nil otherwise
+//  simple string   reply, nil
+//  nil             nil, ErrNil
+//  other           nil, error
+func Scalar(reply interface{}, err error) (interface{}, error) {
+	if err != nil {
+		return nil, err
+	}
+	switch reply := reply.(type) {
+	case int64, string:
+		return reply, nil
+	case []byte:
+		s := string(reply)
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return n, nil
+		}
+		// Require a leading digit, sign or point to keep words such as
+		// "nan" and "infinity" as bytes.
+		if s != "" && strings.IndexByte("+-.0123456789", s[0]) >= 0 {
+			if f, err := strconv.ParseFloat(s, 64); err == nil {
+				return f, nil
+			}
+		}
+		return reply, nil
+	case nil:
+		return nil, ErrNil
+	case Error:
+		return nil, reply
+	}
+	return nil, fmt.Errorf("redigo: unexpected type for Scalar, got type %T", reply)
+}
+
+// GeoDist is a helper that converts a GEODIST command reply to a distance.
+// The boolean result is false when one or both members are missing, in
+// which case the server replies with nil. If err is not equal to nil, then
+// GeoDist returns 0, false, err.
+func GeoDist(reply interface{}, err error) (float64, bool, error) {
+	d, err := Float64(reply, err)
+	if err == ErrNil {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return d, true, nil
+}
+
 // String is a helper that converts a command reply to a string. If err is not
 // equal to nil, then String returns "", err. Otherwise String converts the
 // reply to a string as follows:
@@ -192,6 +377,33 @@ func Bytes(reply interface{}, err error) ([]byte, error) {
 	return nil, fmt.Errorf("redigo: unexpected type for Bytes, got type %T", reply)
 }
 
+// StatusReply is a helper that checks a simple string command reply against
+// the expected status such as "OK", "PONG" or "RESET". The comparison is
+// case-insensitive. If err is not equal to nil, then StatusReply returns err.
+// Otherwise StatusReply checks the reply as follows:
+//
+//  Reply type      Result
+//  simple string   nil if reply matches expected, error otherwise
+//  nil             ErrNil
+//  other           error
+func StatusReply(reply interface{}, err error, expected string) error {
+	if err != nil {
+		return err
+	}
+	switch reply := reply.(type) {
+	case string:
+		if strings.EqualFold(reply, expected) {
+			return nil
+		}
+		return fmt.Errorf("redigo: unexpected status %q, expected %q", reply, expected)
+	case nil:
+		return ErrNil
+	case Error:
+		return reply
+	}
+	return fmt.Errorf("redigo: unexpected type for StatusReply, got type %T", reply)
+}
+
 // Bool is a helper that converts a command reply to a boolean. If err is not
 // equal to nil, then Bool returns false, err. Otherwise Bool converts the
 // reply to boolean as follows:
@@ -218,6 +430,48 @@ func Bool(reply interface{}, err error) (bool, error) {
 	return false, fmt.Errorf("redigo: unexpected type for Bool, got type %T", reply)
 }
 
+// IntBool is a helper that converts an integer command reply to a boolean.
+// Commands such as SMOVE, RENAMENX, HSETNX, EXPIRE, PERSIST, MOVE and SETNX
+// reply with 1 on success and 0 on failure. If err is not equal to nil, then
+// IntBool returns false, err. Otherwise IntBool converts the reply to boolean
+// as follows:
+//
+//  Reply type      Result
+//  integer         value == 1, nil
+//  nil             false, ErrNil
+//  other           false, error
+func IntBool(reply interface{}, err error) (bool, error) {
+	if err != nil {
+		return false, err
+	}
+	switch reply := reply.(type) {
+	case int64:
+		return reply == 1, nil
+	case nil:
+		return false, ErrNil
+	case Error:
+		return false, reply
+	}
+	return false, fmt.Errorf("redigo: unexpected type for IntBool, got type %T", reply)
+}
+
+// KeyType is a helper that converts a TYPE command reply to a RedisType. If
+// err is not equal to nil, then KeyType returns RedisTypeNone, err. A missing
+// key is reported by the server as type "none" and is returned as
+// RedisTypeNone with a nil error.
+func KeyType(reply interface{}, err error) (RedisType, error) {
+	s, err := String(reply, err)
+	if err != nil {
+		return RedisTypeNone, err
+	}
+	for t, name := range redisTypeNames {
+		if s == name {
+			return RedisType(t), nil
+		}
+	}
+	return RedisTypeNone, fmt.Errorf("redigo: unknown type %q for KeyType", s)
+}
+
 // MultiBulk is a helper that converts an array command reply to a []interface{}.
 //
 // Deprecated: Use Values instead.
@@ -291,6 +545,96 @@ func Float64s(reply interface{}, err error) ([]float64, error) {
 	return result, err
 }
 
+// Float32s is a helper that converts an array command reply to a []float32.
+// If err is not equal to nil, then Float32s returns nil, err. Nil array items
+// are converted to 0 in the output slice. Float32s returns an error if an
+// array item is not a bulk string or nil, and an error wrapping
+// strconv.ErrRange if an array item overflows a float32.
+func Float32s(reply interface{}, err error) ([]float32, error) {
+	var result []float32
+	err = sliceHelper(reply, err, "Float32s", func(n int) { result = make([]float32, n) }, func(i int, v interface{}) error {
+		switch v := v.(type) {
+		case []byte:
+			f, err := strconv.ParseFloat(string(v), 32)
+			result[i] = float32(f)
+			return err
+		case Error:
+			return v
+		default:
+			return fmt.Errorf("redigo: unexpected element type for Float32s, got type %T", v)
+		}
+	})
+	return result, err
+}
+
+// Bools is a helper that converts an array command reply to a []bool. If err
+// is not equal to nil, then Bools returns nil, err. Nil array items are
+// converted to false in the output slice. Bools converts the array items as
+// follows:
+//
+//  Item type       Result
+//  integer         value != 0
+//  bulk string     strconv.ParseBool(item)
+//  nil             false
+//  other           error
+func Bools(reply interface{}, err error) ([]bool, error) {
+	var result []bool
+	err = sliceHelper(reply, err, "Bools", func(n int) { result = make([]bool, n) }, func(i int, v interface{}) error {
+		switch v := v.(type) {
+		case int64:
+			result[i] = v != 0
+			return nil
+		case []byte:
+			b, err := strconv.ParseBool(string(v))
+			result[i] = b
+			return err
+		case Error:
+			return v
+		default:
+			return fmt.Errorf("redigo: unexpected element type for Bools, got type %T", v)
+		}
+	})
+	return result, err
+}
+
+// ZScore is a helper that converts a ZSCORE command reply to a score. The
+// boolean result is false when the member or key does not exist, in which
+// case the server replies with nil. If err is not equal to nil, then ZScore
+// returns 0, false, err.
+func ZScore(reply interface{}, err error) (float64, bool, error) {
+	score, err := Float64(reply, err)
+	if err == ErrNil {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return score, true, nil
+}
+
+// ZMScores is a helper that converts a ZMSCORE command reply to a slice of
+// scores. The score of a member that does not exist is nil. If err is not
+// equal to nil, then ZMScores returns nil, err.
+func ZMScores(reply interface{}, err error) ([]*float64, error) {
+	var result []*float64
+	err = sliceHelper(reply, err, "ZMScores", func(n int) { result = make([]*float64, n) }, func(i int, v interface{}) error {
+		switch v := v.(type) {
+		case []byte:
+			f, err := strconv.ParseFloat(string(v), 64)
+			if err != nil {
+				return err
+			}
+			result[i] = &f
+			return nil
+		case Error:
+			return v
+		default:
+			return fmt.Errorf("redigo: unexpected element type for ZMScores, got type %T", v)
+		}
+	})
+	return result, err
+}
+
 // Strings is a helper that converts an array command reply to a []string. If
 // err is not equal to nil, then Strings returns nil, err. Nil array items are
 // converted to "" in the output slice. Strings returns an error if an array
@@ -314,6 +658,144 @@ func Strings(reply interface{}, err error) ([]string, error) {
 	return result, err
 }
 
+// StringsAppend is a helper that converts an array command reply to strings
+// and appends them to dst, returning the extended slice. Nil array items are
+// appended as "". Applications can reuse the capacity of a slice across calls
+// by passing dst[:0]. If err is not equal to nil or the reply cannot be
+// converted, then StringsAppend returns dst unchanged and the error.
+func StringsAppend(dst []string, reply interface{}, err error) ([]string, error) {
+	base := len(dst)
+	err = sliceHelper(reply, err, "StringsAppend", func(n int) {
+		for i := 0; i < n; i++ {
+			dst = append(dst, "")
+		}
+	}, func(i int, v interface{}) error {
+		switch v := v.(type) {
+		case string:
+			dst[base+i] = v
+			return nil
+		case []byte:
+			dst[base+i] = string(v)
+			return nil
+		case Error:
+			return v
+		default:
+			return fmt.Errorf("redigo: unexpected element type for StringsAppend, got type %T", v)
+		}
+	})
+	if err != nil {
+		return dst[:base], err
+	}
+	return dst, nil
+}
+
+// RandMembers is a helper that converts the reply of the SRANDMEMBER,
+// ZRANDMEMBER or HRANDFIELD command with a count to a []string. When the
+// count is negative, the server can return the same member more than once
+// and more members than the size of the collection. RandMembers returns the
+// members as received without removing duplicates. Use RandMembersUnique
+// for positive counts.
+func RandMembers(reply interface{}, err error) ([]string, error) {
+	return Strings(reply, err)
+}
+
+// RandMembersUnique is like RandMembers, but it is intended for positive
+// counts where the server returns distinct members. RandMembersUnique
+// returns an error if the reply contains a duplicate member.
+func RandMembersUnique(reply interface{}, err error) ([]string, error) {
+	members, err := Strings(reply, err)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]struct{}, len(members))
+	for _, m := range members {
+		if _, ok := seen[m]; ok {
+			return nil, fmt.Errorf("redigo: RandMembersUnique got duplicate member %q", m)
+		}
+		seen[m] = struct{}{}
+	}
+	return members, nil
+}
+
+// HRandFields is a helper that converts the reply of the HRANDFIELD command
+// with a count and without WITHVALUES to a []string of field names. When the
+// count is negative, the server can return the same field more than once.
+// HRandFields preserves the duplicates. An empty hash returns an empty slice.
+func HRandFields(reply interface{}, err error) ([]string, error) {
+	return RandMembers(reply, err)
+}
+
+// HRandField is a helper that converts the reply of the HRANDFIELD command
+// without a count to a field name. If the hash is empty or does not exist,
+// then HRandField returns ErrNil.
+func HRandField(reply interface{}, err error) (string, error) {
+	return String(reply, err)
+}
+
+// SetResult is a helper that converts an array command reply to a set of
+// strings. Use SetResult for the replies of set algebra commands such as
+// SINTER, SUNION and SDIFF and for SMEMBERS when the application needs
+// membership tests. Use Strings to preserve the order of the reply.
+func SetResult(reply interface{}, err error) (map[string]struct{}, error) {
+	var result map[string]struct{}
+	err = sliceHelper(reply, err, "SetResult", func(n int) { result = make(map[string]struct{}, n) }, func(i int, v interface{}) error {
+		switch v := v.(type) {
+		case string:
+			result[v] = struct{}{}
+			return nil
+		case []byte:
+			result[string(v)] = struct{}{}
+			return nil
+		case Error:
+			return v
+		default:
+			return fmt.Errorf("redigo: unexpected element type for SetResult, got type %T", v)
+		}
+	})
+	return result, err
+}
+
+// Lines is a helper that converts a human readable command reply to a
+// []string with one element per line. Commands such as LATENCY DOCTOR and
+// MEMORY DOCTOR reply with a single multi-line bulk string. HELP subcommands
+// reply with an array of lines. If err is not equal to nil, then Lines
+// returns nil, err. Otherwise, Lines converts the reply as follows:
+//
+//  Reply type      Result
+//  bulk string     reply split on "\n", nil
+//  simple string   reply split on "\n", nil
+//  array           Strings(reply), nil
+//  nil             nil, ErrNil
+//  other           nil, error
+func Lines(reply interface{}, err error) ([]string, error) {
+	if err != nil {
+		return nil, err
+	}
+	switch reply := reply.(type) {
+	case []byte:
+		return splitLines(string(reply)), nil
+	case string:
+		return splitLines(reply), nil
+	case []interface{}:
+		return Strings(reply, nil)
+	case nil:
+		return nil, ErrNil
+	case Error:
+		return nil, reply
+	}
+	return nil, fmt.Errorf("redigo: unexpected type for Lines, got type %T", reply)
+}
+
+// splitLines splits s into lines, removing line terminators.
+func splitLines(s string) []string {
+	s = strings.TrimSuffix(strings.TrimSuffix(s, "\n"), "\r")
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSuffix(line, "\r")
+	}
+	return lines
+}
+
 // ByteSlices is a helper that converts an array command reply to a [][]byte.
 // If err is not equal to nil, then ByteSlices returns nil, err. Nil array
 // items are stay nil. ByteSlices returns an error if an array item is not a
@@ -334,6 +816,34 @@ func ByteSlices(reply interface{}, err error) ([][]byte, error) {
 	return result, err
 }
 
+// BytesAppend is a helper that converts an array command reply to byte
+// slices and appends them to dst, returning the extended slice. Nil array
+// items are appended as nil. Applications can reuse the capacity of a slice
+// across calls by passing dst[:0]. If err is not equal to nil or the reply
+// cannot be converted, then BytesAppend returns dst unchanged and the error.
+func BytesAppend(dst [][]byte, reply interface{}, err error) ([][]byte, error) {
+	base := len(dst)
+	err = sliceHelper(reply, err, "BytesAppend", func(n int) {
+		for i := 0; i < n; i++ {
+			dst = append(dst, nil)
+		}
+	}, func(i int, v interface{}) error {
+		switch v := v.(type) {
+		case []byte:
+			dst[base+i] = v
+			return nil
+		case Error:
+			return v
+		default:
+			return fmt.Errorf("redigo: unexpected element type for BytesAppend, got type %T", v)
+		}
+	})
+	if err != nil {
+		return dst[:base], err
+	}
+	return dst, nil
+}
+
 // Int64s is a helper that converts an array command reply to a []int64.
 // If err is not equal to nil, then Int64s returns nil, err. Nil array
 // items are stay nil. Int64s returns an error if an array item is not a
@@ -358,6 +868,38 @@ func Int64s(reply interface{}, err error) ([]int64, error) {
 	return result, err
 }
 
+// Int64sAppend is a helper that converts an array command reply to int64
+// values and appends them to dst, returning the extended slice. Nil array
+// items are appended as 0. Applications can reuse the capacity of a slice
+// across calls by passing dst[:0]. If err is not equal to nil or the reply
+// cannot be converted, then Int64sAppend returns dst unchanged and the error.
+func Int64sAppend(dst []int64, reply interface{}, err error) ([]int64, error) {
+	base := len(dst)
+	err = sliceHelper(reply, err, "Int64sAppend", func(n int) {
+		for i := 0; i < n; i++ {
+			dst = append(dst, 0)
+		}
+	}, func(i int, v interface{}) error {
+		switch v := v.(type) {
+		case int64:
+			dst[base+i] = v
+			return nil
+		case []byte:
+			n, err := strconv.ParseInt(string(v), 10, 64)
+			dst[base+i] = n
+			return err
+		case Error:
+			return v
+		default:
+			return fmt.Errorf("redigo: unexpected element type for Int64sAppend, got type %T", v)
+		}
+	})
+	if err != nil {
+		return dst[:base], err
+	}
+	return dst, nil
+}
+
 // Ints is a helper that converts an array command reply to a []int.
 // If err is not equal to nil, then Ints returns nil, err. Nil array
 // items are stay nil. Ints returns an error if an array item is not a
@@ -645,3 +1187,504 @@ func SlowLogs(result interface{}, err error) ([]SlowLog, error) {
 	}
 	return logs, nil
 }
+
+// XMessages is a helper that converts an array of stream entries to a
+// []XMessage. The XRANGE, XREVRANGE, XCLAIM and XAUTOCLAIM commands
+// return entries in this format.
+func XMessages(reply interface{}, err error) ([]XMessage, error) {
+	values, err := Values(reply, err)
+	if err != nil {
+		return nil, err
+	}
+	messages := make([]XMessage, len(values))
+	for i, v := range values {
+		entry, ok := v.([]interface{})
+		if !ok || len(entry) != 2 {
+			return nil, fmt.Errorf("redigo: XMessages element[%d] not a two element array, got %T", i, v)
+		}
+		messages[i].ID, err = String(entry[0], nil)
+		if err != nil {
+			return nil, fmt.Errorf("redigo: XMessages element[%d] ID: %w", i, err)
+		}
+		if entry[1] == nil {
+			continue
+		}
+		messages[i].Fields, err = StringMap(entry[1], nil)
+		if err != nil {
+			return nil, fmt.Errorf("redigo: XMessages element[%d] fields: %w", i, err)
+		}
+	}
+	return messages, nil
+}
+
+// ClusterNodes is a helper that parses the reply of the CLUSTER NODES
+// command.
+func ClusterNodes(reply interface{}, err error) ([]ClusterNode, error) {
+	s, err := String(reply, err)
+	if err != nil {
+		return nil, err
+	}
+	var nodes []ClusterNode
+	for _, line := range splitLines(s) {
+		if line == "" {
+			continue
+		}
+		node, err := parseClusterNode(line)
+		if err != nil {
+			return nil, fmt.Errorf("redigo: ClusterNodes %q: %w", line, err)
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes, nil
+}
+
+func parseClusterNode(line string) (ClusterNode, error) {
+	var node ClusterNode
+	fields := strings.Fields(line)
+	if len(fields) < 8 {
+		return node, fmt.Errorf("expected at least 8 fields, got %d", len(fields))
+	}
+	node.ID = fields[0]
+	node.Addr = fields[1]
+	if i := strings.IndexAny(node.Addr, "@,"); i >= 0 {
+		node.Addr = node.Addr[:i]
+	}
+	node.Flags = strings.Split(fields[2], ",")
+	if fields[3] != "-" {
+		node.Master = fields[3]
+	}
+	var err error
+	if node.PingSent, err = strconv.ParseInt(fields[4], 10, 64); err != nil {
+		return node, err
+	}
+	if node.PongRecv, err = strconv.ParseInt(fields[5], 10, 64); err != nil {
+		return node, err
+	}
+	if node.ConfigEpoch, err = strconv.ParseInt(fields[6], 10, 64); err != nil {
+		return node, err
+	}
+	node.LinkState = fields[7]
+	for _, slot := range fields[8:] {
+		if strings.HasPrefix(slot, "[") {
+			// Slot being imported or migrated.
+			continue
+		}
+		start, end := slot, slot
+		if i := strings.IndexByte(slot, '-'); i >= 0 {
+			start, end = slot[:i], slot[i+1:]
+		}
+		var r [2]int
+		if r[0], err = strconv.Atoi(start); err != nil {
+			return node, err
+		}
+		if r[1], err = strconv.Atoi(end); err != nil {
+			return node, err
+		}
+		node.Slots = append(node.Slots, r)
+	}
+	return node, nil
+}
+
+// PubSubChannels is a helper that converts the reply of the PUBSUB CHANNELS
+// or PUBSUB SHARDCHANNELS command to the list of active channels.
+func PubSubChannels(reply interface{}, err error) ([]string, error) {
+	return Strings(reply, err)
+}
+
+// PubSubNumSub is a helper that converts the reply of the PUBSUB NUMSUB or
+// PUBSUB SHARDNUMSUB command to a map of channel to number of subscribers.
+func PubSubNumSub(reply interface{}, err error) (map[string]int64, error) {
+	return Int64Map(reply, err)
+}
+
+// PubSubNumPat is a helper that converts the reply of the PUBSUB NUMPAT
+// command to the number of pattern subscriptions.
+func PubSubNumPat(reply interface{}, err error) (int64, error) {
+	return Int64(reply, err)
+}
+
+// ZPopResults is a helper that converts the reply of the ZPOPMIN or ZPOPMAX
+// command to a []ZMember. The reply is an array of alternating members and
+// scores. An empty array reply, returned when nothing is popped, is converted
+// to an empty slice. A nil reply returns ErrNil.
+func ZPopResults(reply interface{}, err error) ([]ZMember, error) {
+	values, err := Values(reply, err)
+	if err != nil {
+		return nil, err
+	}
+	if len(values)%2 != 0 {
+		return nil, fmt.Errorf("redigo: ZPopResults expects even number of values result, got %d", len(values))
+	}
+	members := make([]ZMember, len(values)/2)
+	for i := range members {
+		m := &members[i]
+		if m.Member, err = String(values[2*i], nil); err != nil {
+			return nil, fmt.Errorf("redigo: ZPopResults member: %w", err)
+		}
+		if m.Score, err = Float64(values[2*i+1], nil); err != nil {
+			return nil, fmt.Errorf("redigo: ZPopResults score: %w", err)
+		}
+	}
+	return members, nil
+}
+
+// scanValues splits the reply of a SCAN family command into the cursor and
+// the array of elements.
+func scanValues(reply interface{}, err error, name string) (uint64, interface{}, error) {
+	values, err := Values(reply, err)
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(values) != 2 {
+		return 0, nil, fmt.Errorf("redigo: %s expects two values, got %d", name, len(values))
+	}
+	p, err := Bytes(values[0], nil)
+	if err != nil {
+		return 0, nil, fmt.Errorf("redigo: %s cursor: %w", name, err)
+	}
+	cursor, err := strconv.ParseUint(string(p), 10, 64)
+	if err != nil {
+		return 0, nil, fmt.Errorf("redigo: %s cursor: %w", name, err)
+	}
+	return cursor, values[1], nil
+}
+
+// ScanResult is a helper that converts the reply of the SCAN, SSCAN, HSCAN
+// or ZSCAN command to the cursor for the next call and the elements. The
+// iteration is complete when the cursor is zero.
+func ScanResult(reply interface{}, err error) (uint64, []string, error) {
+	cursor, elements, err := scanValues(reply, err, "ScanResult")
+	if err != nil {
+		return 0, nil, err
+	}
+	keys, err := Strings(elements, nil)
+	if err != nil {
+		return 0, nil, fmt.Errorf("redigo: ScanResult elements: %w", err)
+	}
+	return cursor, keys, nil
+}
+
+// ScanResultBytes is like ScanResult but returns the elements as byte
+// slices for keys and members that are not valid UTF-8.
+func ScanResultBytes(reply interface{}, err error) (uint64, [][]byte, error) {
+	cursor, elements, err := scanValues(reply, err, "ScanResultBytes")
+	if err != nil {
+		return 0, nil, err
+	}
+	keys, err := ByteSlices(elements, nil)
+	if err != nil {
+		return 0, nil, fmt.Errorf("redigo: ScanResultBytes elements: %w", err)
+	}
+	return cursor, keys, nil
+}
+
+// KeyValues is a helper that converts the reply of the LMPOP, BLMPOP,
+// BLPOP or BRPOP command to the key and the popped elements. A nil reply,
+// returned when no element is popped, returns ErrNil.
+func KeyValues(reply interface{}, err error) (string, []string, error) {
+	values, err := Values(reply, err)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(values) != 2 {
+		return "", nil, fmt.Errorf("redigo: KeyValues expects two values, got %d", len(values))
+	}
+	key, err := String(values[0], nil)
+	if err != nil {
+		return "", nil, fmt.Errorf("redigo: KeyValues key: %w", err)
+	}
+	switch v := values[1].(type) {
+	case []byte:
+		// BLPOP and BRPOP reply with a single element.
+		return key, []string{string(v)}, nil
+	}
+	elements, err := Strings(values[1], nil)
+	if err != nil {
+		return "", nil, fmt.Errorf("redigo: KeyValues elements: %w", err)
+	}
+	return key, elements, nil
+}
+
+// XInfoConsumers is a helper that converts the reply of the XINFO CONSUMERS
+// command to a []XConsumer.
+func XInfoConsumers(reply interface{}, err error) ([]XConsumer, error) {
+	values, err := Values(reply, err)
+	if err != nil {
+		return nil, err
+	}
+	consumers := make([]XConsumer, len(values))
+	for i, v := range values {
+		c := &consumers[i]
+		err := mapHelper(v, nil, "XInfoConsumers", func(int) {}, func(key string, v interface{}) error {
+			var err error
+			switch key {
+			case "name":
+				c.Name, err = String(v, nil)
+			case "pending":
+				c.Pending, err = Int64(v, nil)
+			case "idle":
+				var ms int64
+				ms, err = Int64(v, nil)
+				c.Idle = time.Duration(ms) * time.Millisecond
+			case "inactive":
+				var ms int64
+				ms, err = Int64(v, nil)
+				if ms < 0 {
+					c.Inactive = -1
+				} else {
+					c.Inactive = time.Duration(ms) * time.Millisecond
+				}
+			}
+			if err != nil {
+				return fmt.Errorf("redigo: XInfoConsumers %s: %w", key, err)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return consumers, nil
+}
+
+// XAutoClaim is a helper that parses the XAUTOCLAIM command reply into the
+// cursor for the next call, the claimed entries and the IDs of entries that
+// were deleted from the stream. Servers prior to Redis 7.0 do not return
+// deleted IDs. On those servers, deleted is nil.
+func XAutoClaim(reply interface{}, err error) (cursor string, messages []XMessage, deleted []string, _ error) {
+	values, err := Values(reply, err)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	if len(values) != 2 && len(values) != 3 {
+		return "", nil, nil, fmt.Errorf("redigo: XAutoClaim expects two or three values, got %d", len(values))
+	}
+	cursor, err = String(values[0], nil)
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("redigo: XAutoClaim cursor: %w", err)
+	}
+	messages, err = XMessages(values[1], nil)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	if len(values) == 3 {
+		deleted, err = Strings(values[2], nil)
+		if err != nil {
+			return "", nil, nil, fmt.Errorf("redigo: XAutoClaim deleted IDs: %w", err)
+		}
+	}
+	return cursor, messages, deleted, nil
+}
+
+// ACLUser is a helper that parses the ACL GETUSER command reply into an
+// ACLUserInfo. Fields not known to this package are stored in the Extra
+// field. A reply of nil for a missing user returns ErrNil.
+func ACLUser(reply interface{}, err error) (ACLUserInfo, error) {
+	var u ACLUserInfo
+	err = mapHelper(reply, err, "ACLUser",
+		func(n int) {}, func(key string, v interface{}) error {
+			var err error
+			switch key {
+			case "flags":
+				u.Flags, err = Strings(v, nil)
+			case "passwords":
+				u.Passwords, err = Strings(v, nil)
+			case "commands":
+				u.Commands, err = String(v, nil)
+			case "keys":
+				u.Keys, err = aclPatterns(v)
+			case "channels":
+				u.Channels, err = aclPatterns(v)
+			case "selectors":
+				var selectors []interface{}
+				selectors, err = Values(v, nil)
+				for _, s := range selectors {
+					var m map[string]string
+					if m, err = StringMap(s, nil); err != nil {
+						break
+					}
+					u.Selectors = append(u.Selectors, m)
+				}
+			default:
+				if u.Extra == nil {
+					u.Extra = make(map[string]interface{})
+				}
+				u.Extra[key] = v
+			}
+			if err != nil {
+				return fmt.Errorf("redigo: ACLUser %s: %w", key, err)
+			}
+			return nil
+		},
+	)
+	return u, err
+}
+
+// aclPatterns converts an ACL GETUSER key or channel pattern reply to a
+// slice of patterns.
+func aclPatterns(v interface{}) ([]string, error) {
+	switch v := v.(type) {
+	case []byte:
+		return strings.Fields(string(v)), nil
+	case string:
+		return strings.Fields(v), nil
+	}
+	return Strings(v, nil)
+}
+
+// LCSMatches is a helper that parses the reply of the LCS command with the
+// IDX option and optionally the MINMATCHLEN and WITHMATCHLEN options.
+func LCSMatches(reply interface{}, err error) (LCSResult, error) {
+	var r LCSResult
+	err = mapHelper(reply, err, "LCSMatches",
+		func(n int) {}, func(key string, v interface{}) error {
+			switch key {
+			case "len":
+				n, err := Int64(v, nil)
+				if err != nil {
+					return fmt.Errorf("redigo: LCSMatches len: %w", err)
+				}
+				r.MatchLen = n
+			case "matches":
+				matches, err := Values(v, nil)
+				if err != nil {
+					return fmt.Errorf("redigo: LCSMatches matches: %w", err)
+				}
+				r.Matches = make([]LCSMatch, len(matches))
+				for i, m := range matches {
+					if err := parseLCSMatch(&r.Matches[i], m); err != nil {
+						return fmt.Errorf("redigo: LCSMatches match[%d]: %w", i, err)
+					}
+				}
+			}
+			return nil
+		},
+	)
+	return r, err
+}
+
+func parseLCSMatch(m *LCSMatch, v interface{}) error {
+	values, err := Values(v, nil)
+	if err != nil {
+		return err
+	}
+	if len(values) != 2 && len(values) != 3 {
+		return fmt.Errorf("expected two or three values, got %d", len(values))
+	}
+	for i, r := range []*[2]int64{&m.A, &m.B} {
+		pos, err := Int64s(values[i], nil)
+		if err != nil {
+			return err
+		}
+		if len(pos) != 2 {
+			return fmt.Errorf("expected range of two positions, got %d", len(pos))
+		}
+		r[0], r[1] = pos[0], pos[1]
+	}
+	if len(values) == 3 {
+		m.Len, err = Int64(values[2], nil)
+	}
+	return err
+}
+
+// DebugObject is a helper that parses the reply of the DEBUG OBJECT command.
+// The reply is a list of space separated key:value fields that varies
+// between server versions. Fields other than those in DebugObjectInfo are
+// stored in the Extra map.
+func DebugObject(reply interface{}, err error) (DebugObjectInfo, error) {
+	var info DebugObjectInfo
+	s, err := String(reply, err)
+	if err != nil {
+		return info, err
+	}
+	s = strings.TrimPrefix(s, "Value ")
+	for _, field := range strings.Fields(s) {
+		i := strings.IndexByte(field, ':')
+		if i < 0 {
+			return info, fmt.Errorf("redigo: DebugObject unexpected field %q", field)
+		}
+		k, v := field[:i], field[i+1:]
+		var p *int64
+		switch k {
+		case "refcount":
+			p = &info.RefCount
+		case "serializedlength":
+			p = &info.SerializedLength
+		case "lru":
+			p = &info.LRU
+		case "lru_seconds_idle":
+			p = &info.LRUSecondsIdle
+		case "encoding":
+			info.Encoding = v
+			continue
+		default:
+			if info.Extra == nil {
+				info.Extra = make(map[string]string)
+			}
+			info.Extra[k] = v
+			continue
+		}
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return info, fmt.Errorf("redigo: DebugObject %s: %w", k, err)
+		}
+		*p = n
+	}
+	return info, nil
+}
+
+// ReplyToJSON renders a reply as JSON for debugging and logging. The reply
+// is rendered as follows:
+//
+//  Reply type         Result
+//  simple string      string
+//  bulk string        string if the bytes are valid UTF-8, otherwise an
+//                     object with the base64 encoded bytes in a "base64" field
+//  integer            number
+//  nil                null
+//  array              array of the rendered elements
+//  error              object with the message in an "error" field
+//
+// ReplyToJSON also renders the float64 and bool values returned by value
+// codecs and decodes a RawReply before rendering. Other types return an
+// error.
+func ReplyToJSON(reply interface{}) ([]byte, error) {
+	v, err := jsonValue(reply)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
+
+// jsonValue converts reply to a value that encoding/json renders as
+// documented by ReplyToJSON.
+func jsonValue(reply interface{}) (interface{}, error) {
+	switch reply := reply.(type) {
+	case nil, string, int64, float64, bool:
+		return reply, nil
+	case []byte:
+		if utf8.Valid(reply) {
+			return string(reply), nil
+		}
+		return map[string]string{"base64": base64.StdEncoding.EncodeToString(reply)}, nil
+	case Error:
+		return map[string]string{"error": string(reply)}, nil
+	case RawReply:
+		r, err := reply.Decode()
+		if err != nil {
+			return nil, err
+		}
+		return jsonValue(r)
+	case []interface{}:
+		values := make([]interface{}, len(reply))
+		for i, r := range reply {
+			v, err := jsonValue(r)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = v
+		}
+		return values, nil
+	}
+	return nil, fmt.Errorf("redigo: unexpected type for ReplyToJSON, got type %T", reply)
+}
@@ -15,6 +15,7 @@
 package redis_test
 
 import (
+	"errors"
 	"fmt"
 	"math"
 	"reflect"
@@ -23,6 +24,7 @@ import (
 	"time"
 
 	"github.com/gomodule/redigo/redis"
+	"github.com/stretchr/testify/require"
 )
 
 var (
@@ -103,6 +105,31 @@ var replyTests = []struct {
 		ve(redis.Float64s([]interface{}{[]byte("1.234"), []byte("5.678")}, nil)),
 		ve([]float64{1.234, 5.678}, nil),
 	},
+	{
+		"float32s([v1, nil, v2])",
+		ve(redis.Float32s([]interface{}{[]byte("1.25"), nil, []byte("-0.5")}, nil)),
+		ve([]float32{1.25, 0, -0.5}, nil),
+	},
+	{
+		"float32s([1, int64])",
+		ve(redis.Float32s([]interface{}{[]byte("1"), int64(2)}, nil)),
+		ve([]float32{1, 0}, errors.New("redigo: unexpected element type for Float32s, got type int64")),
+	},
+	{
+		"bools([1, 0, true, nil, 0])",
+		ve(redis.Bools([]interface{}{int64(1), int64(0), []byte("true"), nil, []byte("0")}, nil)),
+		ve([]bool{true, false, true, false, false}, nil),
+	},
+	{
+		"bools([1, status])",
+		ve(redis.Bools([]interface{}{int64(1), "OK"}, nil)),
+		ve([]bool{true, false}, errors.New("redigo: unexpected element type for Bools, got type string")),
+	},
+	{
+		"bools(nil)",
+		ve(redis.Bools(nil, nil)),
+		ve([]bool(nil), redis.ErrNil),
+	},
 	{
 		"values([v1, v2])",
 		ve(redis.Values([]interface{}{[]byte("v1"), []byte("v2")}, nil)),
@@ -118,6 +145,16 @@ var replyTests = []struct {
 		ve(redis.Float64([]byte("1.0"), nil)),
 		ve(float64(1.0), nil),
 	},
+	{
+		"float32(1.5)",
+		ve(redis.Float32([]byte("1.5"), nil)),
+		ve(float32(1.5), nil),
+	},
+	{
+		"float32(nil)",
+		ve(redis.Float32(nil, nil)),
+		ve(float32(0), redis.ErrNil),
+	},
 	{
 		"float64(nil)",
 		ve(redis.Float64(nil, nil)),
@@ -138,6 +175,91 @@ var replyTests = []struct {
 		ve(redis.Uint64(int64(-1), nil)),
 		ve(uint64(0), redis.ErrNegativeInt(-1)),
 	},
+	{
+		"intBool(1)",
+		ve(redis.IntBool(int64(1), nil)),
+		ve(true, nil),
+	},
+	{
+		"intBool(0)",
+		ve(redis.IntBool(int64(0), nil)),
+		ve(false, nil),
+	},
+	{
+		"intBool(nil)",
+		ve(redis.IntBool(nil, nil)),
+		ve(false, redis.ErrNil),
+	},
+	{
+		"intBool([]byte)",
+		ve(redis.IntBool([]byte("1"), nil)),
+		ve(false, errors.New("redigo: unexpected type for IntBool, got type []uint8")),
+	},
+	{
+		"lines(bulk string)",
+		ve(redis.Lines([]byte("line 1\r\nline 2\n"), nil)),
+		ve([]string{"line 1", "line 2"}, nil),
+	},
+	{
+		"lines([bulk string, simple string])",
+		ve(redis.Lines([]interface{}{[]byte("line 1"), "line 2"}, nil)),
+		ve([]string{"line 1", "line 2"}, nil),
+	},
+	{
+		"randMembers([a, a, b])",
+		ve(redis.RandMembers([]interface{}{[]byte("a"), []byte("a"), []byte("b")}, nil)),
+		ve([]string{"a", "a", "b"}, nil),
+	},
+	{
+		"randMembersUnique([a, b])",
+		ve(redis.RandMembersUnique([]interface{}{[]byte("a"), []byte("b")}, nil)),
+		ve([]string{"a", "b"}, nil),
+	},
+	{
+		"randMembersUnique([a, b, a])",
+		ve(redis.RandMembersUnique([]interface{}{[]byte("a"), []byte("b"), []byte("a")}, nil)),
+		ve([]string(nil), errors.New(`redigo: RandMembersUnique got duplicate member "a"`)),
+	},
+	{
+		"pubSubChannels([a, b])",
+		ve(redis.PubSubChannels([]interface{}{[]byte("a"), []byte("b")}, nil)),
+		ve([]string{"a", "b"}, nil),
+	},
+	{
+		"pubSubNumSub([a, 2, b, 0])",
+		ve(redis.PubSubNumSub([]interface{}{[]byte("a"), int64(2), []byte("b"), int64(0)}, nil)),
+		ve(map[string]int64{"a": 2, "b": 0}, nil),
+	},
+	{
+		"pubSubNumPat(3)",
+		ve(redis.PubSubNumPat(int64(3), nil)),
+		ve(int64(3), nil),
+	},
+	{
+		"setResult([apple, pear, fig])",
+		ve(redis.SetResult([]interface{}{[]byte("apple"), []byte("pear"), []byte("fig")}, nil)),
+		ve(map[string]struct{}{"apple": {}, "pear": {}, "fig": {}}, nil),
+	},
+	{
+		"setResult([])",
+		ve(redis.SetResult([]interface{}{}, nil)),
+		ve(map[string]struct{}{}, nil),
+	},
+	{
+		"setResult(int)",
+		ve(redis.SetResult(int64(1), nil)),
+		ve(map[string]struct{}(nil), errors.New("redigo: unexpected type for SetResult, got type int64")),
+	},
+	{
+		"storeCount(3)",
+		ve(redis.StoreCount(int64(3), nil)),
+		ve(int64(3), nil),
+	},
+	{
+		"storeCount(0)",
+		ve(redis.StoreCount(int64(0), nil)),
+		ve(int64(0), nil),
+	},
 	{
 		"positions([[1, 2], nil, [3, 4]])",
 		ve(redis.Positions([]interface{}{[]interface{}{[]byte("1"), []byte("2")}, nil, []interface{}{[]byte("3"), []byte("4")}}, nil)),
@@ -170,6 +292,514 @@ func TestReply(t *testing.T) {
 	}
 }
 
+func TestFloat32Range(t *testing.T) {
+	_, err := redis.Float32([]byte("1e39"), nil)
+	require.True(t, errors.Is(err, strconv.ErrRange), "Float32 returned %v", err)
+
+	_, err = redis.Float32s([]interface{}{[]byte("1"), []byte("-1e39")}, nil)
+	require.True(t, errors.Is(err, strconv.ErrRange), "Float32s returned %v", err)
+
+	f, err := redis.Float32([]byte("3.4e38"), nil)
+	require.NoError(t, err)
+	require.Equal(t, float32(3.4e38), f)
+}
+
+func TestDuration(t *testing.T) {
+	tests := []struct {
+		name  string
+		reply interface{}
+		unit  time.Duration
+		want  time.Duration
+		err   error
+	}{
+		{"seconds", int64(90), time.Second, 90 * time.Second, nil},
+		{"milliseconds", int64(1500), time.Millisecond, 1500 * time.Millisecond, nil},
+		{"bulk string", []byte("12"), time.Second, 12 * time.Second, nil},
+		{"decimal seconds", []byte("1.5"), time.Second, 1500 * time.Millisecond, nil},
+		{"decimal milliseconds", []byte("0.25"), time.Millisecond, 250 * time.Microsecond, nil},
+		{"decimal no expiry", []byte("-1.0"), time.Second, -1, nil},
+		{"no expiry", int64(-1), time.Second, -1, nil},
+		{"missing key", int64(-2), time.Millisecond, -2, nil},
+		{"nil", nil, time.Second, 0, redis.ErrNil},
+		{"error", redis.Error("ERR x"), time.Second, 0, redis.Error("ERR x")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := redis.Duration(tt.reply, nil, tt.unit)
+			require.Equal(t, tt.err, err)
+			require.Equal(t, tt.want, d)
+		})
+	}
+
+	for _, reply := range []string{"x", "NaN", "inf", "1e300"} {
+		_, err := redis.Duration([]byte(reply), nil, time.Second)
+		require.Error(t, err, reply)
+	}
+	_, err := redis.Duration("OK", nil, time.Second)
+	require.EqualError(t, err, "redigo: unexpected type for Duration, got type string")
+	_, err = redis.Duration(int64(math.MaxInt64), nil, time.Second)
+	require.Error(t, err)
+}
+
+func TestKeyType(t *testing.T) {
+	for _, want := range []redis.RedisType{
+		redis.RedisTypeNone,
+		redis.RedisTypeString,
+		redis.RedisTypeList,
+		redis.RedisTypeSet,
+		redis.RedisTypeZSet,
+		redis.RedisTypeHash,
+		redis.RedisTypeStream,
+	} {
+		got, err := redis.KeyType(want.String(), nil)
+		if err != nil {
+			t.Errorf("KeyType(%q) returned error %v", want.String(), err)
+			continue
+		}
+		if got != want {
+			t.Errorf("KeyType(%q) = %v, want %v", want.String(), got, want)
+		}
+	}
+	if _, err := redis.KeyType("vectorset2", nil); err == nil {
+		t.Errorf("KeyType(unknown) did not return an error")
+	}
+}
+
+func TestXInfoConsumers(t *testing.T) {
+	reply := []interface{}{
+		[]interface{}{
+			[]byte("name"), []byte("Alice"),
+			[]byte("pending"), int64(1),
+			[]byte("idle"), int64(9104628),
+			[]byte("inactive"), int64(18104698),
+		},
+		[]interface{}{
+			[]byte("name"), []byte("Bob"),
+			[]byte("pending"), int64(0),
+			[]byte("idle"), int64(83841983),
+			[]byte("inactive"), int64(-1),
+		},
+		// Redis before 7.2 does not report inactive.
+		[]interface{}{
+			[]byte("name"), []byte("Carol"),
+			[]byte("pending"), int64(2),
+			[]byte("idle"), int64(500),
+		},
+	}
+	consumers, err := redis.XInfoConsumers(reply, nil)
+	require.NoError(t, err)
+	require.Equal(t, []redis.XConsumer{
+		{Name: "Alice", Pending: 1, Idle: 9104628 * time.Millisecond, Inactive: 18104698 * time.Millisecond},
+		{Name: "Bob", Pending: 0, Idle: 83841983 * time.Millisecond, Inactive: -1},
+		{Name: "Carol", Pending: 2, Idle: 500 * time.Millisecond},
+	}, consumers)
+
+	_, err = redis.XInfoConsumers([]interface{}{[]interface{}{[]byte("pending"), []byte("x")}}, nil)
+	require.Error(t, err)
+}
+
+func TestXAutoClaim(t *testing.T) {
+	messages := []interface{}{
+		[]interface{}{[]byte("1-0"), []interface{}{[]byte("f"), []byte("v")}},
+		[]interface{}{[]byte("2-0"), nil},
+	}
+	wantMessages := []redis.XMessage{
+		{ID: "1-0", Fields: map[string]string{"f": "v"}},
+		{ID: "2-0"},
+	}
+
+	cursor, got, deleted, err := redis.XAutoClaim([]interface{}{[]byte("3-0"), messages, []interface{}{[]byte("4-0")}}, nil)
+	if err != nil {
+		t.Fatalf("XAutoClaim returned error %v", err)
+	}
+	if cursor != "3-0" || !reflect.DeepEqual(got, wantMessages) || !reflect.DeepEqual(deleted, []string{"4-0"}) {
+		t.Errorf("XAutoClaim = %q, %+v, %q", cursor, got, deleted)
+	}
+
+	// Servers before Redis 7.0 do not return deleted IDs.
+	cursor, got, deleted, err = redis.XAutoClaim([]interface{}{[]byte("0-0"), messages}, nil)
+	if err != nil {
+		t.Fatalf("XAutoClaim returned error %v", err)
+	}
+	if cursor != "0-0" || !reflect.DeepEqual(got, wantMessages) || deleted != nil {
+		t.Errorf("XAutoClaim = %q, %+v, %q", cursor, got, deleted)
+	}
+}
+
+func TestACLUser(t *testing.T) {
+	tests := []struct {
+		name  string
+		reply []interface{}
+		want  redis.ACLUserInfo
+	}{
+		{
+			"redis 6.0",
+			[]interface{}{
+				[]byte("flags"), []interface{}{[]byte("on"), []byte("allkeys")},
+				[]byte("passwords"), []interface{}{[]byte("5e88")},
+				[]byte("commands"), []byte("+@all"),
+				[]byte("keys"), []interface{}{[]byte("*")},
+			},
+			redis.ACLUserInfo{
+				Flags:     []string{"on", "allkeys"},
+				Passwords: []string{"5e88"},
+				Commands:  "+@all",
+				Keys:      []string{"*"},
+			},
+		},
+		{
+			"redis 7.0",
+			[]interface{}{
+				[]byte("flags"), []interface{}{[]byte("on")},
+				[]byte("passwords"), []interface{}{},
+				[]byte("commands"), []byte("-@all +get"),
+				[]byte("keys"), []byte("~foo:* %R~bar"),
+				[]byte("channels"), []byte("&*"),
+				[]byte("selectors"), []interface{}{
+					[]interface{}{[]byte("commands"), []byte("+set"), []byte("keys"), []byte("~baz")},
+				},
+				[]byte("future"), int64(1),
+			},
+			redis.ACLUserInfo{
+				Flags:     []string{"on"},
+				Passwords: []string{},
+				Commands:  "-@all +get",
+				Keys:      []string{"~foo:*", "%R~bar"},
+				Channels:  []string{"&*"},
+				Selectors: []map[string]string{{"commands": "+set", "keys": "~baz"}},
+				Extra:     map[string]interface{}{"future": int64(1)},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := redis.ACLUser(tt.reply, nil)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+
+	_, err := redis.ACLUser(nil, nil)
+	require.Equal(t, redis.ErrNil, err)
+}
+
+func TestLCSMatches(t *testing.T) {
+	// LCS key1 key2 IDX MINMATCHLEN 4 WITHMATCHLEN with key1 = "ohmytext" and
+	// key2 = "mynewtext".
+	reply := []interface{}{
+		[]byte("matches"),
+		[]interface{}{
+			[]interface{}{
+				[]interface{}{int64(4), int64(7)},
+				[]interface{}{int64(5), int64(8)},
+				int64(4),
+			},
+		},
+		[]byte("len"),
+		int64(6),
+	}
+	want := redis.LCSResult{
+		MatchLen: 6,
+		Matches:  []redis.LCSMatch{{A: [2]int64{4, 7}, B: [2]int64{5, 8}, Len: 4}},
+	}
+	got, err := redis.LCSMatches(reply, nil)
+	require.NoError(t, err)
+	require.Equal(t, want, got)
+}
+
+func TestGeoDist(t *testing.T) {
+	d, ok, err := redis.GeoDist([]byte("166274.1516"), nil)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, 166274.1516, d)
+
+	_, ok, err = redis.GeoDist(nil, nil)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	_, _, err = redis.GeoDist([]byte("x"), nil)
+	require.Error(t, err)
+}
+
+func TestDebugObject(t *testing.T) {
+	info, err := redis.DebugObject("Value at:0x7f2c refcount:1 encoding:quicklist serializedlength:19 lru:8537 lru_seconds_idle:42 ql_nodes:1", nil)
+	require.NoError(t, err)
+	require.Equal(t, redis.DebugObjectInfo{
+		RefCount:         1,
+		Encoding:         "quicklist",
+		SerializedLength: 19,
+		LRU:              8537,
+		LRUSecondsIdle:   42,
+		Extra:            map[string]string{"at": "0x7f2c", "ql_nodes": "1"},
+	}, info)
+
+	_, err = redis.DebugObject("refcount:x", nil)
+	require.Error(t, err)
+
+	_, err = redis.DebugObject(redis.Error("ERR no such key"), nil)
+	require.EqualError(t, err, "ERR no such key")
+}
+
+func TestStatusReply(t *testing.T) {
+	require.NoError(t, redis.StatusReply("OK", nil, "OK"))
+	require.NoError(t, redis.StatusReply("PONG", nil, "pong"))
+	require.EqualError(t, redis.StatusReply("QUEUED", nil, "OK"), `redigo: unexpected status "QUEUED", expected "OK"`)
+	require.ErrorIs(t, redis.StatusReply(nil, nil, "OK"), redis.ErrNil)
+	require.EqualError(t, redis.StatusReply(redis.Error("ERR x"), nil, "OK"), "ERR x")
+	require.Error(t, redis.StatusReply([]byte("OK"), nil, "OK"))
+}
+
+func TestSliceAppend(t *testing.T) {
+	t.Run("strings", func(t *testing.T) {
+		dst := []string{"a", "stale", "stale"}
+		got, err := redis.StringsAppend(dst[:1], []interface{}{[]byte("b"), nil}, nil)
+		require.NoError(t, err)
+		require.Equal(t, []string{"a", "b", ""}, got)
+		require.Equal(t, &dst[0], &got[0], "capacity not reused")
+
+		got, err = redis.StringsAppend(got, []interface{}{int64(1)}, nil)
+		require.Error(t, err)
+		require.Equal(t, []string{"a", "b", ""}, got)
+
+		got, err = redis.StringsAppend(got, nil, nil)
+		require.Equal(t, redis.ErrNil, err)
+		require.Len(t, got, 3)
+	})
+
+	t.Run("int64s", func(t *testing.T) {
+		dst := []int64{9, 9}
+		got, err := redis.Int64sAppend(dst[:0], []interface{}{int64(1), []byte("2"), nil}, nil)
+		require.NoError(t, err)
+		require.Equal(t, []int64{1, 2, 0}, got)
+
+		got, err = redis.Int64sAppend(got, []interface{}{[]byte("x")}, nil)
+		require.Error(t, err)
+		require.Equal(t, []int64{1, 2, 0}, got)
+	})
+
+	t.Run("bytes", func(t *testing.T) {
+		dst := [][]byte{[]byte("stale")}
+		got, err := redis.BytesAppend(dst[:0], []interface{}{[]byte("a"), nil}, nil)
+		require.NoError(t, err)
+		require.Equal(t, [][]byte{[]byte("a"), nil}, got)
+
+		got, err = redis.BytesAppend(got, redis.Error("ERR"), nil)
+		require.Equal(t, redis.Error("ERR"), err)
+		require.Len(t, got, 2)
+	})
+}
+
+var benchmarkMembers = func() []interface{} {
+	members := make([]interface{}, 100)
+	for i := range members {
+		members[i] = []byte(strconv.Itoa(i))
+	}
+	return members
+}()
+
+func BenchmarkInt64s(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := redis.Int64s(benchmarkMembers, nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkInt64sAppend(b *testing.B) {
+	b.ReportAllocs()
+	var dst []int64
+	for i := 0; i < b.N; i++ {
+		var err error
+		if dst, err = redis.Int64sAppend(dst[:0], benchmarkMembers, nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestScalar(t *testing.T) {
+	tests := []struct {
+		reply interface{}
+		want  interface{}
+	}{
+		{[]byte("42"), int64(42)},
+		{[]byte("-7"), int64(-7)},
+		{[]byte("3.25"), 3.25},
+		{[]byte("1e3"), 1e3},
+		{[]byte("-inf"), math.Inf(-1)},
+		{[]byte("hello"), []byte("hello")},
+		{[]byte("nan"), []byte("nan")},
+		{[]byte("12abc"), []byte("12abc")},
+		{[]byte(""), []byte("")},
+		{int64(5), int64(5)},
+		{"OK", "OK"},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%q", tt.reply), func(t *testing.T) {
+			got, err := redis.Scalar(tt.reply, nil)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+
+	_, err := redis.Scalar(nil, nil)
+	require.Equal(t, redis.ErrNil, err)
+	_, err = redis.Scalar([]interface{}{}, nil)
+	require.Error(t, err)
+}
+
+func TestZScore(t *testing.T) {
+	score, ok, err := redis.ZScore([]byte("1.5"), nil)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, 1.5, score)
+
+	score, ok, err = redis.ZScore(nil, nil)
+	require.NoError(t, err)
+	require.False(t, ok)
+	require.Equal(t, 0.0, score)
+
+	_, _, err = redis.ZScore(redis.Error("WRONGTYPE"), nil)
+	require.Equal(t, redis.Error("WRONGTYPE"), err)
+}
+
+func TestZMScores(t *testing.T) {
+	scores, err := redis.ZMScores([]interface{}{[]byte("1"), nil, []byte("-2.5")}, nil)
+	require.NoError(t, err)
+	require.Len(t, scores, 3)
+	require.Equal(t, 1.0, *scores[0])
+	require.Nil(t, scores[1])
+	require.Equal(t, -2.5, *scores[2])
+
+	_, err = redis.ZMScores([]interface{}{[]byte("x")}, nil)
+	require.Error(t, err)
+}
+
+func TestHRandFields(t *testing.T) {
+	fields, err := redis.HRandFields([]interface{}{[]byte("a"), []byte("b"), []byte("a"), []byte("a")}, nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{"a", "b", "a", "a"}, fields)
+
+	fields, err = redis.HRandFields([]interface{}{}, nil)
+	require.NoError(t, err)
+	require.Empty(t, fields)
+
+	field, err := redis.HRandField([]byte("a"), nil)
+	require.NoError(t, err)
+	require.Equal(t, "a", field)
+
+	_, err = redis.HRandField(nil, nil)
+	require.Equal(t, redis.ErrNil, err)
+}
+
+func TestScanResult(t *testing.T) {
+	cursor, keys, err := redis.ScanResult([]interface{}{[]byte("17"), []interface{}{[]byte("a"), []byte("b")}}, nil)
+	require.NoError(t, err)
+	require.Equal(t, uint64(17), cursor)
+	require.Equal(t, []string{"a", "b"}, keys)
+
+	cursor, keys, err = redis.ScanResult([]interface{}{[]byte("0"), []interface{}{}}, nil)
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), cursor)
+	require.Empty(t, keys)
+
+	cursor, bkeys, err := redis.ScanResultBytes([]interface{}{[]byte("18446744073709551615"), []interface{}{[]byte("\xff")}}, nil)
+	require.NoError(t, err)
+	require.Equal(t, uint64(18446744073709551615), cursor)
+	require.Equal(t, [][]byte{[]byte("\xff")}, bkeys)
+
+	_, _, err = redis.ScanResult([]interface{}{[]byte("x"), []interface{}{}}, nil)
+	require.Error(t, err)
+	_, _, err = redis.ScanResult([]interface{}{[]byte("0")}, nil)
+	require.Error(t, err)
+}
+
+func TestKeyValues(t *testing.T) {
+	key, values, err := redis.KeyValues([]interface{}{[]byte("k"), []interface{}{[]byte("a"), []byte("b")}}, nil)
+	require.NoError(t, err)
+	require.Equal(t, "k", key)
+	require.Equal(t, []string{"a", "b"}, values)
+
+	key, values, err = redis.KeyValues([]interface{}{[]byte("k"), []byte("a")}, nil)
+	require.NoError(t, err)
+	require.Equal(t, "k", key)
+	require.Equal(t, []string{"a"}, values)
+
+	_, _, err = redis.KeyValues(nil, nil)
+	require.Equal(t, redis.ErrNil, err)
+	_, _, err = redis.KeyValues([]interface{}{[]byte("k")}, nil)
+	require.Error(t, err)
+}
+
+func TestReplyToJSON(t *testing.T) {
+	tests := []struct {
+		name  string
+		reply interface{}
+		want  string
+	}{
+		{"simple string", "OK", `"OK"`},
+		{"bulk string", []byte("hello"), `"hello"`},
+		{"binary bulk string", []byte{0xff, 0x00, 0xfe}, `{"base64":"/wD+"}`},
+		{"integer", int64(-42), `-42`},
+		{"nil", nil, `null`},
+		{"error", redis.Error("ERR x"), `{"error":"ERR x"}`},
+		{"float", 1.5, `1.5`},
+		{"bool", true, `true`},
+		{"empty array", []interface{}{}, `[]`},
+		{"nested array", []interface{}{[]byte("a"), int64(1), nil, []interface{}{"b", []byte{0x80}, redis.Error("ERR y")}}, `["a",1,null,["b",{"base64":"gA=="},{"error":"ERR y"}]]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := redis.ReplyToJSON(tt.reply)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, string(p))
+		})
+	}
+
+	_, err := redis.ReplyToJSON([]interface{}{struct{}{}})
+	require.Error(t, err)
+}
+
+func TestClusterNodes(t *testing.T) {
+	reply := []byte("" +
+		"07c37dfeb235213a872192d90877d0cd55635b91 127.0.0.1:30004@31004,hostname4 slave e7d1eecce10fd6bb5eb35b9f99a514335d9ba9ca 0 1426238317239 4 connected\n" +
+		"67ed2db8d677e59ec4a4cefb06858cf2a1a89fa1 127.0.0.1:30002@31002,hostname2 master - 0 1426238316232 2 connected 5461-10922\n" +
+		"e7d1eecce10fd6bb5eb35b9f99a514335d9ba9ca 127.0.0.1:30001@31001,hostname1 myself,master - 0 0 1 connected 0-5460 10923 [5461->-67ed2db8d677e59ec4a4cefb06858cf2a1a89fa1]\n")
+	nodes, err := redis.ClusterNodes(reply, nil)
+	require.NoError(t, err)
+	require.Equal(t, []redis.ClusterNode{
+		{
+			ID:          "07c37dfeb235213a872192d90877d0cd55635b91",
+			Addr:        "127.0.0.1:30004",
+			Flags:       []string{"slave"},
+			Master:      "e7d1eecce10fd6bb5eb35b9f99a514335d9ba9ca",
+			PongRecv:    1426238317239,
+			ConfigEpoch: 4,
+			LinkState:   "connected",
+		},
+		{
+			ID:          "67ed2db8d677e59ec4a4cefb06858cf2a1a89fa1",
+			Addr:        "127.0.0.1:30002",
+			Flags:       []string{"master"},
+			PongRecv:    1426238316232,
+			ConfigEpoch: 2,
+			LinkState:   "connected",
+			Slots:       [][2]int{{5461, 10922}},
+		},
+		{
+			ID:          "e7d1eecce10fd6bb5eb35b9f99a514335d9ba9ca",
+			Addr:        "127.0.0.1:30001",
+			Flags:       []string{"myself", "master"},
+			ConfigEpoch: 1,
+			LinkState:   "connected",
+			Slots:       [][2]int{{0, 5460}, {10923, 10923}},
+		},
+	}, nodes)
+
+	_, err = redis.ClusterNodes([]byte("id 127.0.0.1:30001 master -\n"), nil)
+	require.Error(t, err)
+}
+
 func TestSlowLog(t *testing.T) {
 	c, err := dial()
 	if err != nil {
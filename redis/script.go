@@ -18,6 +18,7 @@ import (
 	"context"
 	"crypto/sha1"
 	"encoding/hex"
+	"errors"
 	"io"
 	"strings"
 )
@@ -67,7 +68,8 @@ func (s *Script) DoContext(ctx context.Context, c Conn, keysAndArgs ...interface
 		return nil, errContextNotSupported
 	}
 	v, err := cwt.DoContext(ctx, "EVALSHA", s.args(s.hash, keysAndArgs)...)
-	if e, ok := err.(Error); ok && strings.HasPrefix(string(e), "NOSCRIPT ") {
+	var e Error
+	if errors.As(err, &e) && strings.HasPrefix(string(e), "NOSCRIPT ") {
 		v, err = cwt.DoContext(ctx, "EVAL", s.args(s.src, keysAndArgs)...)
 	}
 	return v, err
@@ -79,7 +81,8 @@ func (s *Script) DoContext(ctx context.Context, c Conn, keysAndArgs ...interface
 // causing the script to load).
 func (s *Script) Do(c Conn, keysAndArgs ...interface{}) (interface{}, error) {
 	v, err := c.Do("EVALSHA", s.args(s.hash, keysAndArgs)...)
-	if e, ok := err.(Error); ok && strings.HasPrefix(string(e), "NOSCRIPT ") {
+	var e Error
+	if errors.As(err, &e) && strings.HasPrefix(string(e), "NOSCRIPT ") {
 		v, err = c.Do("EVAL", s.args(s.src, keysAndArgs)...)
 	}
 	return v, err
@@ -0,0 +1,166 @@
+// Copyright 2012 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// Package redisotel traces Redigo commands with OpenTelemetry.
+//
+// Wrap a connection with NewConn or the connections of a pool with WrapPool
+// to start a client span for each command issued with Do, DoContext,
+// DoWithTimeout or DoWithKey. The context passed to DoContext is the parent
+// of the span. Commands sent with Send are not traced.
+//
+// The span is named after the command and has the attributes db.system and
+// db.statement. The statement is the command name only. Command arguments
+// are never recorded because they can contain keys, values and passwords.
+package redisotel
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/gomodule/redigo/redisotel"
+
+// Option specifies an option for tracing connections.
+type Option struct {
+	f func(*config)
+}
+
+type config struct {
+	tracerProvider trace.TracerProvider
+	peerName       string
+}
+
+// WithTracerProvider specifies the tracer provider for creating spans. The
+// global tracer provider is used by default.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return Option{func(cfg *config) {
+		cfg.tracerProvider = tp
+	}}
+}
+
+// WithPeerName specifies the host name of the server recorded in the
+// net.peer.name attribute.
+func WithPeerName(name string) Option {
+	return Option{func(cfg *config) {
+		cfg.peerName = name
+	}}
+}
+
+func newTracer(options []Option) (trace.Tracer, []attribute.KeyValue) {
+	cfg := config{tracerProvider: otel.GetTracerProvider()}
+	for _, option := range options {
+		option.f(&cfg)
+	}
+	attrs := []attribute.KeyValue{attribute.String("db.system", "redis")}
+	if cfg.peerName != "" {
+		attrs = append(attrs, attribute.String("net.peer.name", cfg.peerName))
+	}
+	return cfg.tracerProvider.Tracer(instrumentationName), attrs
+}
+
+// NewConn returns a connection that traces the commands issued on c.
+func NewConn(c redis.Conn, options ...Option) redis.Conn {
+	tracer, attrs := newTracer(options)
+	return &conn{Conn: c, tracer: tracer, attrs: attrs}
+}
+
+// WrapPool sets the Dial and DialContext functions of p to trace the
+// commands issued on the connections created by the functions. WrapPool
+// must be called before the pool is used.
+func WrapPool(p *redis.Pool, options ...Option) {
+	tracer, attrs := newTracer(options)
+	if dial := p.Dial; dial != nil {
+		p.Dial = func() (redis.Conn, error) {
+			c, err := dial()
+			if err != nil {
+				return nil, err
+			}
+			return &conn{Conn: c, tracer: tracer, attrs: attrs}, nil
+		}
+	}
+	if dial := p.DialContext; dial != nil {
+		p.DialContext = func(ctx context.Context) (redis.Conn, error) {
+			c, err := dial(ctx)
+			if err != nil {
+				return nil, err
+			}
+			return &conn{Conn: c, tracer: tracer, attrs: attrs}, nil
+		}
+	}
+}
+
+type conn struct {
+	redis.Conn
+	tracer trace.Tracer
+	attrs  []attribute.KeyValue
+}
+
+// do issues a command in a span. The empty command flushes the connection
+// and is not traced.
+func (c *conn) do(ctx context.Context, commandName string, fn func() (interface{}, error)) (interface{}, error) {
+	if commandName == "" {
+		return fn()
+	}
+	name := strings.ToUpper(commandName)
+	_, span := c.tracer.Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(c.attrs...),
+		trace.WithAttributes(attribute.String("db.statement", name)))
+	defer span.End()
+	reply, err := fn()
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return reply, err
+}
+
+func (c *conn) Do(commandName string, args ...interface{}) (interface{}, error) {
+	return c.do(context.Background(), commandName, func() (interface{}, error) {
+		return c.Conn.Do(commandName, args...)
+	})
+}
+
+func (c *conn) DoContext(ctx context.Context, commandName string, args ...interface{}) (interface{}, error) {
+	return c.do(ctx, commandName, func() (interface{}, error) {
+		return redis.DoContext(c.Conn, ctx, commandName, args...)
+	})
+}
+
+func (c *conn) DoWithTimeout(timeout time.Duration, commandName string, args ...interface{}) (interface{}, error) {
+	return c.do(context.Background(), commandName, func() (interface{}, error) {
+		return redis.DoWithTimeout(c.Conn, timeout, commandName, args...)
+	})
+}
+
+func (c *conn) DoWithKey(routingKey string, commandName string, args ...interface{}) (interface{}, error) {
+	return c.do(context.Background(), commandName, func() (interface{}, error) {
+		return redis.DoWithKey(c.Conn, routingKey, commandName, args...)
+	})
+}
+
+func (c *conn) ReceiveContext(ctx context.Context) (interface{}, error) {
+	return redis.ReceiveContext(c.Conn, ctx)
+}
+
+func (c *conn) ReceiveWithTimeout(timeout time.Duration) (interface{}, error) {
+	return redis.ReceiveWithTimeout(c.Conn, timeout)
+}
@@ -0,0 +1,102 @@
+// Copyright 2012 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package redisotel_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/gomodule/redigo/redisotel"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// fakeConn replies to GET with a value and to other commands with an error.
+type fakeConn struct {
+	redis.Conn
+}
+
+func (fakeConn) Do(commandName string, args ...interface{}) (interface{}, error) {
+	if strings.EqualFold(commandName, "GET") {
+		return []byte("v"), nil
+	}
+	return nil, redis.Error("ERR unknown command")
+}
+
+func (c fakeConn) DoContext(ctx context.Context, commandName string, args ...interface{}) (interface{}, error) {
+	return c.Do(commandName, args...)
+}
+
+func (fakeConn) ReceiveContext(context.Context) (interface{}, error) {
+	return nil, redis.ErrNil
+}
+
+func TestConn(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+	c := redisotel.NewConn(fakeConn{}, redisotel.WithTracerProvider(tp), redisotel.WithPeerName("cache.example.com"))
+
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "parent")
+	v, err := redis.String(redis.DoContext(c, ctx, "get", "secret-key"))
+	require.NoError(t, err)
+	require.Equal(t, "v", v)
+	parent.End()
+
+	_, err = c.Do("BAD", "x")
+	require.Error(t, err)
+
+	// The flush command is not traced.
+	_, err = c.Do("")
+	require.Error(t, err)
+
+	spans := sr.Ended()
+	require.Len(t, spans, 3)
+
+	get := spans[0]
+	require.Equal(t, "GET", get.Name())
+	require.Equal(t, parent.SpanContext().SpanID(), get.Parent().SpanID())
+	require.ElementsMatch(t, []attribute.KeyValue{
+		attribute.String("db.system", "redis"),
+		attribute.String("net.peer.name", "cache.example.com"),
+		attribute.String("db.statement", "GET"),
+	}, get.Attributes())
+	require.Equal(t, codes.Unset, get.Status().Code)
+
+	bad := spans[2]
+	require.Equal(t, "BAD", bad.Name())
+	require.False(t, bad.Parent().IsValid())
+	require.Equal(t, codes.Error, bad.Status().Code)
+	require.Len(t, bad.Events(), 1)
+}
+
+func TestWrapPool(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+	p := &redis.Pool{Dial: func() (redis.Conn, error) { return fakeConn{}, nil }}
+	redisotel.WrapPool(p, redisotel.WithTracerProvider(tp))
+
+	c := p.Get()
+	_, err := c.Do("GET", "k")
+	require.NoError(t, err)
+
+	spans := sr.Ended()
+	require.Len(t, spans, 1)
+	require.Equal(t, "GET", spans[0].Name())
+}